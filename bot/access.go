@@ -0,0 +1,295 @@
+package bot
+
+import (
+	"cmp"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+
+	"teslamate-bot/config"
+	"teslamate-bot/store"
+)
+
+// accessKey 运行时授权在状态存储中的键
+const accessKey = "access"
+
+// Role 用户角色，数值越大权限越高
+type Role int
+
+const (
+	RoleNone Role = iota
+	RoleViewer
+	RoleController
+	RoleAdmin
+)
+
+// parseRole 解析配置中的角色名称
+func parseRole(name string) Role {
+	switch name {
+	case config.RoleViewer:
+		return RoleViewer
+	case config.RoleController:
+		return RoleController
+	case config.RoleAdmin:
+		return RoleAdmin
+	}
+	return RoleNone
+}
+
+// Name 角色在配置中的名称
+func (r Role) Name() string {
+	switch r {
+	case RoleViewer:
+		return config.RoleViewer
+	case RoleController:
+		return config.RoleController
+	case RoleAdmin:
+		return config.RoleAdmin
+	}
+	return ""
+}
+
+// String 角色显示名称
+func (r Role) String() string {
+	switch r {
+	case RoleViewer:
+		return "查看者"
+	case RoleController:
+		return "控制者"
+	case RoleAdmin:
+		return "管理员"
+	}
+	return "无权限"
+}
+
+// accessOverlay 管理员在运行时修改的授权，叠加在配置文件之上
+type accessOverlay struct {
+	Chats  map[int64]string `json:"chats"`  // 运行时授权的会话及角色
+	Users  map[int64]string `json:"users"`  // 运行时授权的用户及角色
+	Denied []int64          `json:"denied"` // 被移除的配置文件中的会话/用户
+}
+
+// AccessEntry 一条授权记录
+type AccessEntry struct {
+	ID      int64
+	Role    Role
+	Runtime bool // 是否为运行时授权（否则来自配置文件）
+}
+
+// Access 会话与用户的角色分配
+type Access struct {
+	mu      sync.Mutex
+	store   *store.Store
+	chats   map[int64]Role // 配置文件中的会话
+	users   map[int64]Role // 配置文件中的用户
+	overlay accessOverlay
+}
+
+// NewAccess 根据配置创建角色分配（白名单会话使用默认角色），并加载运行时授权
+func NewAccess(cfg config.TelegramConfig, st *store.Store) *Access {
+	a := &Access{
+		store: st,
+		chats: make(map[int64]Role),
+		users: make(map[int64]Role),
+	}
+	defaultRole := parseRole(cfg.DefaultRole)
+	for _, chatID := range cfg.WhitelistChatIDs {
+		a.chats[chatID] = defaultRole
+	}
+	for _, chat := range cfg.Chats {
+		a.chats[chat.ChatID] = parseRole(chat.Role)
+	}
+	for _, user := range cfg.Users {
+		a.users[user.UserID] = parseRole(user.Role)
+	}
+
+	if _, err := st.Get(accessKey, &a.overlay); err != nil {
+		log.Printf("加载运行时授权失败: %v", err)
+	}
+	if a.overlay.Chats == nil {
+		a.overlay.Chats = make(map[int64]string)
+	}
+	if a.overlay.Users == nil {
+		a.overlay.Users = make(map[int64]string)
+	}
+	return a
+}
+
+// Role 获取用户在会话中的角色
+//
+// 会话需在白名单中（用户与Bot的私聊除外），配置了角色的用户使用自己的角色，
+// 否则使用会话角色。
+func (a *Access) Role(chatID, userID int64) Role {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	chatRole, chatKnown := a.chatRole(chatID)
+	userRole, userKnown := a.userRole(userID)
+
+	switch {
+	case userKnown && (chatKnown || chatID == userID):
+		return userRole
+	case chatKnown:
+		return chatRole
+	}
+	return RoleNone
+}
+
+// chatRole 会话角色（运行时授权优先，调用方需持有锁）
+func (a *Access) chatRole(chatID int64) (Role, bool) {
+	if name, ok := a.overlay.Chats[chatID]; ok {
+		return parseRole(name), true
+	}
+	if slices.Contains(a.overlay.Denied, chatID) {
+		return RoleNone, false
+	}
+	role, ok := a.chats[chatID]
+	return role, ok
+}
+
+// userRole 用户角色（运行时授权优先，调用方需持有锁）
+func (a *Access) userRole(userID int64) (Role, bool) {
+	if name, ok := a.overlay.Users[userID]; ok {
+		return parseRole(name), true
+	}
+	if slices.Contains(a.overlay.Denied, userID) {
+		return RoleNone, false
+	}
+	role, ok := a.users[userID]
+	return role, ok
+}
+
+// AllowChat 授权会话
+func (a *Access) AllowChat(chatID int64, role Role) {
+	a.update(func() {
+		a.overlay.Chats[chatID] = role.Name()
+		a.undeny(chatID)
+	})
+}
+
+// AllowUser 授权用户
+func (a *Access) AllowUser(userID int64, role Role) {
+	a.update(func() {
+		a.overlay.Users[userID] = role.Name()
+		a.undeny(userID)
+	})
+}
+
+// undeny 取消对配置文件中会话/用户的移除（调用方需持有锁）
+func (a *Access) undeny(id int64) {
+	a.overlay.Denied = slices.DeleteFunc(a.overlay.Denied, func(d int64) bool { return d == id })
+}
+
+// Deny 移除会话及同ID用户的授权，返回是否存在该授权
+func (a *Access) Deny(id int64) bool {
+	found := false
+	a.update(func() {
+		_, chatKnown := a.chatRole(id)
+		_, userKnown := a.userRole(id)
+		found = chatKnown || userKnown
+
+		delete(a.overlay.Chats, id)
+		delete(a.overlay.Users, id)
+		_, inChats := a.chats[id]
+		_, inUsers := a.users[id]
+		if (inChats || inUsers) && !slices.Contains(a.overlay.Denied, id) {
+			a.overlay.Denied = append(a.overlay.Denied, id)
+		}
+	})
+	return found
+}
+
+// update 修改运行时授权并保存
+func (a *Access) update(fn func()) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	fn()
+	if err := a.store.Put(accessKey, a.overlay); err != nil {
+		log.Printf("保存运行时授权失败: %v", err)
+	}
+}
+
+// Chats 全部已授权会话（按ID排序）
+func (a *Access) Chats() []AccessEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.entries(a.chats, a.overlay.Chats)
+}
+
+// Users 全部已授权用户（按ID排序）
+func (a *Access) Users() []AccessEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.entries(a.users, a.overlay.Users)
+}
+
+// entries 合并配置文件与运行时授权（调用方需持有锁）
+func (a *Access) entries(configured map[int64]Role, runtime map[int64]string) []AccessEntry {
+	var list []AccessEntry
+	for id, name := range runtime {
+		list = append(list, AccessEntry{ID: id, Role: parseRole(name), Runtime: true})
+	}
+	for id, role := range configured {
+		if _, ok := runtime[id]; ok || slices.Contains(a.overlay.Denied, id) {
+			continue
+		}
+		list = append(list, AccessEntry{ID: id, Role: role})
+	}
+	slices.SortFunc(list, func(x, y AccessEntry) int {
+		return cmp.Compare(x.ID, y.ID)
+	})
+	return list
+}
+
+// ChatIDs 可以接收推送的全部会话（含已授权用户的私聊）
+func (a *Access) ChatIDs() []int64 {
+	var ids []int64
+	for _, e := range a.Chats() {
+		ids = append(ids, e.ID)
+	}
+	for _, e := range a.Users() {
+		if !slices.Contains(ids, e.ID) {
+			ids = append(ids, e.ID)
+		}
+	}
+	return ids
+}
+
+// AdminChatIDs 管理员会话（管理员用户的私聊及管理员角色的会话）
+func (a *Access) AdminChatIDs() []int64 {
+	var ids []int64
+	for _, e := range append(a.Chats(), a.Users()...) {
+		if e.Role == RoleAdmin && !slices.Contains(ids, e.ID) {
+			ids = append(ids, e.ID)
+		}
+	}
+	return ids
+}
+
+// controllerCallbacks 需要控制者权限的回调数据前缀（远程控制及修改设置）
+var controllerCallbacks = []string{"control:", "ctl_", "ctlok_", "notify_", "alerts_", "geofence_"}
+
+// commandRole 执行命令所需的最低角色
+func commandRole(command, args string) Role {
+	switch {
+	case command == "allow" || command == "deny" || command == "users" || command == "invite":
+		return RoleAdmin
+	case command == "control":
+		return RoleController
+	case command == "alerts" && strings.TrimSpace(args) != "":
+		return RoleController
+	}
+	return RoleViewer
+}
+
+// callbackRole 处理回调所需的最低角色
+func callbackRole(data string) Role {
+	for _, prefix := range controllerCallbacks {
+		if strings.HasPrefix(data, prefix) {
+			return RoleController
+		}
+	}
+	return RoleViewer
+}
@@ -0,0 +1,171 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// accessAlertInterval 同一会话未授权访问提醒的最短间隔
+const accessAlertInterval = 10 * time.Minute
+
+// accessAlerts 未授权访问提醒的限流记录
+type accessAlerts struct {
+	mu   sync.Mutex
+	last map[int64]time.Time
+}
+
+// allow 判断是否应该提醒该会话的访问尝试，并记录提醒时间
+func (a *accessAlerts) allow(chatID int64, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.last == nil {
+		a.last = make(map[int64]time.Time)
+	}
+	if last, ok := a.last[chatID]; ok && now.Sub(last) < accessAlertInterval {
+		return false
+	}
+	a.last[chatID] = now
+	return true
+}
+
+// notifyUnknownAccess 向管理员推送未授权访问尝试（同一会话限流）
+func (b *Bot) notifyUnknownAccess(chat *tgbotapi.Chat, user *tgbotapi.User, text string) {
+	if !b.accessAlerts.allow(chat.ID, time.Now()) {
+		return
+	}
+
+	alert := b.handler.HandleAccessAttempt(chat, user, text)
+	for _, chatID := range b.access.AdminChatIDs() {
+		if _, err := b.messenger.Send(tgbotapi.NewMessage(chatID, alert)); err != nil {
+			log.Printf("推送未授权访问提醒失败: ChatID=%d, %v", chatID, err)
+		}
+	}
+}
+
+// handleAllowCommand 处理 /allow [user] <ID> [角色] 命令
+func (b *Bot) handleAllowCommand(chatID int64, args string) {
+	const usage = "❓ 用法:\n/allow <会话ID> [viewer|controller|admin]\n/allow user <用户ID> [viewer|controller|admin]"
+
+	fields := strings.Fields(args)
+	isUser := len(fields) > 0 && fields[0] == "user"
+	if isUser {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 || len(fields) > 2 {
+		b.messenger.Send(tgbotapi.NewMessage(chatID, usage))
+		return
+	}
+
+	id, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil || id == 0 {
+		b.messenger.Send(tgbotapi.NewMessage(chatID, usage))
+		return
+	}
+	role := b.defaultRole
+	if len(fields) == 2 {
+		role = parseRole(fields[1])
+		if role == RoleNone {
+			b.messenger.Send(tgbotapi.NewMessage(chatID, usage))
+			return
+		}
+	}
+
+	kind := "会话"
+	if isUser {
+		kind = "用户"
+		b.access.AllowUser(id, role)
+	} else {
+		b.access.AllowChat(id, role)
+	}
+	log.Printf("管理员授权%s: ID=%d, 角色=%s, ChatID=%d", kind, id, role.Name(), chatID)
+	b.messenger.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ 已授权%s %d（%s）", kind, id, role)))
+}
+
+// handleDenyCommand 处理 /deny <ID> 命令
+func (b *Bot) handleDenyCommand(chatID, userID int64, args string) {
+	id, err := strconv.ParseInt(strings.TrimSpace(args), 10, 64)
+	if err != nil || id == 0 {
+		b.messenger.Send(tgbotapi.NewMessage(chatID, "❓ 用法: /deny <会话ID或用户ID>"))
+		return
+	}
+	if id == userID {
+		b.messenger.Send(tgbotapi.NewMessage(chatID, "❓ 不能移除自己的授权"))
+		return
+	}
+
+	if !b.access.Deny(id) {
+		b.messenger.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❓ %d 未被授权", id)))
+		return
+	}
+	log.Printf("管理员移除授权: ID=%d, ChatID=%d", id, chatID)
+	b.messenger.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("🚫 已移除 %d 的授权", id)))
+}
+
+// sendUsers 发送授权列表
+func (b *Bot) sendUsers(chatID int64) {
+	text := b.handler.HandleUsers(b.access.Chats(), b.access.Users())
+	b.messenger.Send(tgbotapi.NewMessage(chatID, text))
+}
+
+// handleInviteCommand 处理 /invite [角色] [有效期] 命令
+func (b *Bot) handleInviteCommand(chatID int64, args string) {
+	const usage = "❓ 用法: /invite [viewer|controller|admin] [有效期，如 30m、12h、7d]"
+
+	role := b.defaultRole
+	ttl := inviteDefaultTTL
+	for _, field := range strings.Fields(args) {
+		if r := parseRole(field); r != RoleNone {
+			role = r
+			continue
+		}
+		d, err := parseTTL(field)
+		if err != nil {
+			b.messenger.Send(tgbotapi.NewMessage(chatID, usage))
+			return
+		}
+		ttl = d
+	}
+
+	code, expiresAt, err := b.invites.Create(role, chatID, ttl, time.Now())
+	if err != nil {
+		log.Printf("%v", err)
+		b.messenger.Send(tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+	log.Printf("管理员生成邀请码: 角色=%s, 有效期至=%s, ChatID=%d", role.Name(), expiresAt.Format(time.RFC3339), chatID)
+
+	msg := tgbotapi.NewMessage(chatID, b.handler.HandleInvite(b.username, code, role, expiresAt))
+	msg.DisableWebPagePreview = true
+	b.messenger.Send(msg)
+}
+
+// redeemInvite 处理 /start <邀请码>，成功时授权当前会话并通知创建者
+func (b *Bot) redeemInvite(message *tgbotapi.Message, code string) {
+	chatID := message.Chat.ID
+
+	inv, ok := b.invites.Redeem(code, time.Now())
+	if !ok {
+		log.Printf("无效的邀请码: ChatID=%d, UserID=%d", chatID, senderID(message.From))
+		b.notifyUnknownAccess(message.Chat, message.From, message.Text)
+		return
+	}
+
+	role := parseRole(inv.Role)
+	b.access.AllowChat(chatID, role)
+	log.Printf("会话通过邀请码加入: ChatID=%d, UserID=%d, 角色=%s", chatID, senderID(message.From), inv.Role)
+
+	b.messenger.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ 已加入，当前会话角色为「%s」", role)))
+	b.sendMainMenu(chatID)
+
+	joined := b.handler.HandleInviteJoined(message.Chat, message.From, role)
+	if _, err := b.messenger.Send(tgbotapi.NewMessage(inv.CreatedBy, joined)); err != nil {
+		log.Printf("通知邀请码创建者失败: ChatID=%d, %v", inv.CreatedBy, err)
+	}
+}
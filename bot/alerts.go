@@ -0,0 +1,192 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"teslamate-bot/models"
+	"teslamate-bot/store"
+)
+
+const (
+	// alertsKey 电量阈值提醒在状态存储中的键
+	alertsKey = "alerts"
+	// alertHysteresis 电量回差（%），电量越过阈值该幅度后才会重新触发提醒
+	alertHysteresis = 3
+)
+
+// lowBatteryOptions /alerts 菜单中低电量阈值的可选值（0 表示关闭）
+var lowBatteryOptions = []int{0, 10, 20, 30}
+
+// targetSOCOptions /alerts 菜单中目标电量的可选值（0 表示关闭）
+var targetSOCOptions = []int{0, 60, 70, 80, 90}
+
+// ChatAlerts 单个会话的电量阈值设置与触发状态
+type ChatAlerts struct {
+	LowBattery int             `json:"low_battery"` // 未插枪时电量低于该值提醒，0 表示关闭
+	TargetSOC  int             `json:"target_soc"`  // 充电时电量达到该值提醒，0 表示关闭
+	Fired      map[string]bool `json:"fired"`       // 各车辆已触发的提醒（如 low:1、target:2）
+}
+
+// Alerts 各会话的电量阈值提醒
+type Alerts struct {
+	mu    sync.Mutex
+	store *store.Store
+	chats map[int64]*ChatAlerts
+}
+
+// NewAlerts 从状态存储加载电量阈值设置
+func NewAlerts(st *store.Store) *Alerts {
+	a := &Alerts{
+		store: st,
+		chats: make(map[int64]*ChatAlerts),
+	}
+	if _, err := st.Get(alertsKey, &a.chats); err != nil {
+		log.Printf("加载电量提醒设置失败: %v", err)
+	}
+	return a
+}
+
+// Get 获取会话的电量阈值设置
+func (a *Alerts) Get(chatID int64) ChatAlerts {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if c, ok := a.chats[chatID]; ok {
+		return *c
+	}
+	return ChatAlerts{}
+}
+
+// SetLowBattery 设置低电量阈值（0 表示关闭）
+func (a *Alerts) SetLowBattery(chatID int64, level int) {
+	a.update(chatID, func(c *ChatAlerts) {
+		c.LowBattery = level
+		c.resetFired("low")
+	})
+}
+
+// SetTargetSOC 设置充电目标电量（0 表示关闭）
+func (a *Alerts) SetTargetSOC(chatID int64, level int) {
+	a.update(chatID, func(c *ChatAlerts) {
+		c.TargetSOC = level
+		c.resetFired("target")
+	})
+}
+
+// resetFired 清除某类提醒在所有车辆上的触发状态
+func (c *ChatAlerts) resetFired(kind string) {
+	for key := range c.Fired {
+		if strings.HasPrefix(key, kind+":") {
+			delete(c.Fired, key)
+		}
+	}
+}
+
+// setFired 记录提醒在某辆车上的触发状态，返回是否发生变化
+func (c *ChatAlerts) setFired(key string, fired bool) bool {
+	if c.Fired[key] == fired {
+		return false
+	}
+	if c.Fired == nil {
+		c.Fired = make(map[string]bool)
+	}
+	if fired {
+		c.Fired[key] = true
+	} else {
+		delete(c.Fired, key)
+	}
+	return true
+}
+
+// update 修改会话设置并保存
+func (a *Alerts) update(chatID int64, fn func(c *ChatAlerts)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	c, ok := a.chats[chatID]
+	if !ok {
+		c = &ChatAlerts{}
+		a.chats[chatID] = c
+	}
+	fn(c)
+	a.save()
+}
+
+// save 保存全部设置（调用方需持有锁）
+func (a *Alerts) save() {
+	if err := a.store.Put(alertsKey, a.chats); err != nil {
+		log.Printf("保存电量提醒设置失败: %v", err)
+	}
+}
+
+// alertsWatcher 按各会话的阈值检查某辆车的电量
+type alertsWatcher struct {
+	carID  int
+	alerts *Alerts
+}
+
+// Name 监控项名称
+func (w *alertsWatcher) Name() string {
+	return carKey(alertsKey, w.carID)
+}
+
+// Check 检查电量是否越过各会话设置的阈值
+func (w *alertsWatcher) Check(ctx context.Context, statusResp *models.StatusResponse) []Notification {
+	status := &statusResp.Data.Status
+	level := status.BatteryDetails.BatteryLevel
+	if level <= 0 {
+		return nil
+	}
+	plugged := status.ChargingDetails.PluggedIn
+	charging := plugged && status.ChargingDetails.ChargingState == "Charging"
+
+	a := w.alerts
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	lowKey := carKey("low", w.carID)
+	targetKey := carKey("target", w.carID)
+
+	var messages []Notification
+	changed := false
+	for chatID, c := range a.chats {
+		if c.LowBattery > 0 {
+			switch {
+			case !c.Fired[lowKey] && !plugged && level < c.LowBattery:
+				changed = c.setFired(lowKey, true) || changed
+				messages = append(messages, Notification{
+					ChatID: chatID,
+					Text: fmt.Sprintf("🪫 电量不足\n━━━━━━━━━━━━━━━━━━━━\n🚗 %s\n🔋 当前电量: %d%%（低于 %d%%）\n📏 续航: %.0f %s",
+						status.DisplayName, level, c.LowBattery,
+						status.BatteryDetails.RatedBatteryRange, statusResp.Data.Units.UnitOfLength),
+				})
+			case c.Fired[lowKey] && level >= c.LowBattery+alertHysteresis:
+				changed = c.setFired(lowKey, false) || changed
+			}
+		}
+
+		if c.TargetSOC > 0 {
+			switch {
+			case !c.Fired[targetKey] && charging && level >= c.TargetSOC:
+				changed = c.setFired(targetKey, true) || changed
+				messages = append(messages, Notification{
+					ChatID: chatID,
+					Text: fmt.Sprintf("🎯 已达到目标电量\n━━━━━━━━━━━━━━━━━━━━\n🚗 %s\n🔋 当前电量: %d%%（目标 %d%%）\n⚡ 充电上限: %d%%",
+						status.DisplayName, level, c.TargetSOC, status.ChargingDetails.ChargeLimitSOC),
+				})
+			case c.Fired[targetKey] && level < c.TargetSOC-alertHysteresis:
+				changed = c.setFired(targetKey, false) || changed
+			}
+		}
+	}
+
+	if changed {
+		a.save()
+	}
+
+	return messages
+}
@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"teslamate-bot/models"
+)
+
+// battery 电量
+func battery(level int) func(s *models.CarStatus) {
+	return func(s *models.CarStatus) {
+		s.BatteryDetails.BatteryLevel = level
+	}
+}
+
+func TestAlertsLowBattery(t *testing.T) {
+	alerts := NewAlerts(newTestStore(t))
+	alerts.SetLowBattery(100, 20)
+	w := &alertsWatcher{carID: 1, alerts: alerts}
+
+	runSteps(t, w, []checkStep{
+		{status: testStatus(t, unplugged, battery(25))},
+		{status: testStatus(t, unplugged, battery(19)), want: []string{"100: 🪫 电量不足"}},
+		{status: testStatus(t, unplugged, battery(15))},
+		// 回差 3%：回升到 22% 不重置，23% 后才会再次提醒
+		{status: testStatus(t, unplugged, battery(22))},
+		{status: testStatus(t, unplugged, battery(19))},
+		{status: testStatus(t, unplugged, battery(23))},
+		{status: testStatus(t, unplugged, battery(19)), want: []string{"100: 🪫 电量不足"}},
+		// 充电后电量回升
+		{status: testStatus(t, charging(30, 5))},
+		// 插枪时不提醒
+		{status: testStatus(t, chargeState("Stopped", 10))},
+		// 没有电量数据时跳过
+		{status: testStatus(t, unplugged, battery(0))},
+	})
+}
+
+func TestAlertsTargetSOC(t *testing.T) {
+	alerts := NewAlerts(newTestStore(t))
+	alerts.SetTargetSOC(100, 80)
+	w := &alertsWatcher{carID: 1, alerts: alerts}
+
+	runSteps(t, w, []checkStep{
+		{status: testStatus(t, charging(75, 5))},
+		{status: testStatus(t, charging(80, 8)), want: []string{"100: 🎯 已达到目标电量"}},
+		{status: testStatus(t, charging(85, 10))},
+		// 回差 3%：降到 77% 不重置，76% 后下次充电再次提醒
+		{status: testStatus(t, unplugged, battery(77))},
+		{status: testStatus(t, charging(80, 2))},
+		{status: testStatus(t, unplugged, battery(76))},
+		{status: testStatus(t, charging(80, 2)), want: []string{"100: 🎯 已达到目标电量"}},
+	})
+
+	// 修改阈值后重新计算
+	alerts.SetTargetSOC(100, 70)
+	runSteps(t, w, []checkStep{
+		{status: testStatus(t, charging(80, 2)), want: []string{"100: 🎯 已达到目标电量"}},
+	})
+}
+
+func TestAlertsPerChat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "teslamate-bot.db")
+	st := openTestStore(t, path)
+	alerts := NewAlerts(st)
+	alerts.SetLowBattery(100, 20)
+	alerts.SetLowBattery(200, 30)
+	alerts.SetLowBattery(300, 0)
+
+	got := titles((&alertsWatcher{carID: 1, alerts: alerts}).Check(context.Background(), testStatus(t, unplugged, battery(25))))
+	if want := []string{"200: 🪫 电量不足"}; !slices.Equal(got, want) {
+		t.Errorf("通知 = %q, 期望 %q", got, want)
+	}
+	got = titles((&alertsWatcher{carID: 1, alerts: alerts}).Check(context.Background(), testStatus(t, unplugged, battery(15))))
+	if want := []string{"100: 🪫 电量不足"}; !slices.Equal(got, want) {
+		t.Errorf("通知 = %q, 期望 %q", got, want)
+	}
+
+	// 重启后不重复提醒，其他车辆单独计算
+	st.Close()
+	alerts = NewAlerts(openTestStore(t, path))
+	runSteps(t, &alertsWatcher{carID: 1, alerts: alerts}, []checkStep{
+		{status: testStatus(t, unplugged, battery(15))},
+	})
+	got = titles((&alertsWatcher{carID: 2, alerts: alerts}).Check(context.Background(), testStatus(t, unplugged, battery(15))))
+	slices.Sort(got)
+	if want := []string{"100: 🪫 电量不足", "200: 🪫 电量不足"}; !slices.Equal(got, want) {
+		t.Errorf("车辆 2 通知 = %q, 期望 %q", got, want)
+	}
+}
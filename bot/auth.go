@@ -0,0 +1,302 @@
+package bot
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"teslamate-bot/config"
+	"teslamate-bot/store"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// totpStep TOTP 时间步长（RFC 6238 默认值）
+	totpStep = 30
+	// totpDigits TOTP 位数
+	totpDigits = 6
+	// totpWindow 允许的前后时间步数，容忍手机与服务器的时钟偏差
+	totpWindow = 1
+	// authPendingTTL 等待输入验证码的有效期
+	authPendingTTL = 2 * time.Minute
+	// authKeyPrefix 用户验证状态在状态存储中的键前缀
+	authKeyPrefix = "auth"
+)
+
+// authFactor 用户的二次验证方式
+type authFactor struct {
+	pin        string
+	totpSecret []byte
+}
+
+// authUserState 用户的验证状态
+//
+// 失败次数、锁定时间与 TOTP 计数器保存在状态存储中，重启后仍然有效；
+// 验证有效期只保存在内存中，重启后需要重新验证。
+type authUserState struct {
+	sessionUntil time.Time
+	Failures     int       `json:"failures"`
+	LockedUntil  time.Time `json:"locked_until"`
+	LastCounter  int64     `json:"last_counter"` // 最近一次使用的 TOTP 计数器，防止验证码重放
+}
+
+// authKey 等待验证的会话与用户
+type authKey struct {
+	chatID int64
+	userID int64
+}
+
+// pendingAuth 验证通过后继续执行的操作
+type pendingAuth struct {
+	resume  func(ctx context.Context)
+	expires time.Time
+}
+
+// authResult 一次验证的结果
+type authResult struct {
+	OK          bool
+	Remaining   int       // 锁定前剩余的尝试次数
+	LockedUntil time.Time // 非零时表示已锁定
+}
+
+// Auth 敏感操作的二次验证（PIN 或 TOTP），验证通过后在一段时间内有效
+type Auth struct {
+	mu        sync.Mutex
+	store     *store.Store
+	session   time.Duration
+	maxFails  int
+	lockout   time.Duration
+	sensitive []string
+	factors   map[int64]authFactor
+	states    map[int64]*authUserState
+	pending   map[authKey]pendingAuth
+}
+
+// NewAuth 根据配置创建二次验证（配置已在加载时校验）
+func NewAuth(cfg config.AuthConfig, st *store.Store) *Auth {
+	a := &Auth{
+		store:     st,
+		session:   time.Duration(cfg.SessionMinutes) * time.Minute,
+		maxFails:  cfg.MaxFailures,
+		lockout:   time.Duration(cfg.LockoutMinutes) * time.Minute,
+		sensitive: cfg.Sensitive,
+		factors:   make(map[int64]authFactor),
+		states:    make(map[int64]*authUserState),
+		pending:   make(map[authKey]pendingAuth),
+	}
+	for _, u := range cfg.Users {
+		factor := authFactor{pin: u.PIN}
+		if u.TOTPSecret != "" {
+			factor.totpSecret, _ = config.DecodeTOTPSecret(u.TOTPSecret)
+		}
+		a.factors[u.UserID] = factor
+	}
+	return a
+}
+
+// Required 判断用户执行该功能前是否需要验证
+func (a *Auth) Required(userID int64, feature string, now time.Time) bool {
+	if !slices.Contains(a.sensitive, feature) {
+		return false
+	}
+	if _, ok := a.factors[userID]; !ok {
+		return false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return !now.Before(a.state(userID).sessionUntil)
+}
+
+// SetPending 记录等待验证的操作，验证通过后执行
+func (a *Auth) SetPending(chatID, userID int64, resume func(ctx context.Context), now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending[authKey{chatID, userID}] = pendingAuth{resume: resume, expires: now.Add(authPendingTTL)}
+}
+
+// TakePending 取出等待验证的操作（不存在或已过期时返回 false）
+func (a *Auth) TakePending(chatID, userID int64, now time.Time) (func(ctx context.Context), bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := authKey{chatID, userID}
+	p, ok := a.pending[key]
+	delete(a.pending, key)
+	if !ok || !now.Before(p.expires) {
+		return nil, false
+	}
+	return p.resume, true
+}
+
+// Verify 校验 PIN 或 TOTP 验证码，通过后开始新的有效期
+func (a *Auth) Verify(userID int64, code string, now time.Time) authResult {
+	factor, ok := a.factors[userID]
+	if !ok {
+		return authResult{}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	st := a.state(userID)
+	if now.Before(st.LockedUntil) {
+		log.Printf("[审计] 二次验证被拒绝（已锁定）: UserID=%d", userID)
+		return authResult{LockedUntil: st.LockedUntil}
+	}
+	defer a.save(userID, st)
+
+	code = strings.TrimSpace(code)
+	method := ""
+	if factor.pin != "" && subtle.ConstantTimeCompare([]byte(code), []byte(factor.pin)) == 1 {
+		method = "PIN"
+	} else if factor.totpSecret != nil {
+		if counter, ok := verifyTOTP(factor.totpSecret, code, now); ok && counter > st.LastCounter {
+			st.LastCounter = counter
+			method = "TOTP"
+		}
+	}
+
+	if method != "" {
+		st.Failures = 0
+		st.sessionUntil = now.Add(a.session)
+		log.Printf("[审计] 二次验证成功（%s）: UserID=%d，有效期至 %s", method, userID, st.sessionUntil.Format(time.RFC3339))
+		return authResult{OK: true}
+	}
+
+	st.Failures++
+	if st.Failures >= a.maxFails {
+		st.Failures = 0
+		st.LockedUntil = now.Add(a.lockout)
+		log.Printf("[审计] 二次验证失败次数过多，已锁定: UserID=%d，至 %s", userID, st.LockedUntil.Format(time.RFC3339))
+		return authResult{LockedUntil: st.LockedUntil}
+	}
+	log.Printf("[审计] 二次验证失败: UserID=%d，连续失败 %d 次", userID, st.Failures)
+	return authResult{Remaining: a.maxFails - st.Failures}
+}
+
+// Lock 立即结束用户的验证有效期
+func (a *Auth) Lock(userID int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.state(userID).sessionUntil = time.Time{}
+	log.Printf("[审计] 用户主动结束验证有效期: UserID=%d", userID)
+}
+
+// Enabled 用户是否配置了二次验证
+func (a *Auth) Enabled(userID int64) bool {
+	_, ok := a.factors[userID]
+	return ok
+}
+
+// state 获取用户验证状态，首次使用时从状态存储恢复（调用方需持有锁）
+func (a *Auth) state(userID int64) *authUserState {
+	st, ok := a.states[userID]
+	if !ok {
+		st = &authUserState{}
+		if _, err := a.store.Get(authStateKey(userID), st); err != nil {
+			log.Printf("恢复二次验证状态失败: UserID=%d, %v", userID, err)
+		}
+		a.states[userID] = st
+	}
+	return st
+}
+
+// save 保存用户验证状态（调用方需持有锁）
+func (a *Auth) save(userID int64, st *authUserState) {
+	if err := a.store.Put(authStateKey(userID), st); err != nil {
+		log.Printf("保存二次验证状态失败: UserID=%d, %v", userID, err)
+	}
+}
+
+// authStateKey 用户验证状态在状态存储中的键
+func authStateKey(userID int64) string {
+	return fmt.Sprintf("%s:%d", authKeyPrefix, userID)
+}
+
+// verifyTOTP 按 RFC 6238 校验验证码，返回匹配的时间步计数器
+func verifyTOTP(secret []byte, code string, now time.Time) (int64, bool) {
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	counter := now.Unix() / totpStep
+	for offset := int64(-totpWindow); offset <= totpWindow; offset++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(secret, counter+offset)), []byte(code)) == 1 {
+			return counter + offset, true
+		}
+	}
+	return 0, false
+}
+
+// totpCode 计算某个时间步的验证码（HMAC-SHA1，RFC 4226 动态截断）
+func totpCode(secret []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// callbackFeature 回调对应的功能名（与命令名一致，用于判断是否需要验证）
+func callbackFeature(data string) string {
+	if action, _, ok := parseCarData(data); ok {
+		data = action
+	}
+	data = strings.TrimPrefix(data, "refresh_")
+	if strings.HasPrefix(data, "ctl_") || strings.HasPrefix(data, "ctlok_") {
+		return "control"
+	}
+	return data
+}
+
+// requestAuth 提示用户输入验证码，验证通过后执行 resume
+func (b *Bot) requestAuth(chat *tgbotapi.Chat, userID int64, resume func(ctx context.Context)) {
+	b.auth.SetPending(chat.ID, userID, resume, time.Now())
+	b.messenger.Send(tgbotapi.NewMessage(chat.ID, b.handler.HandleAuthPrompt(!chat.IsPrivate())))
+}
+
+// handleAuthCode 处理等待验证时收到的文本消息，返回该消息是否被当作验证码处理
+func (b *Bot) handleAuthCode(ctx context.Context, message *tgbotapi.Message) bool {
+	chatID := message.Chat.ID
+	userID := senderID(message.From)
+	now := time.Now()
+
+	resume, ok := b.auth.TakePending(chatID, userID, now)
+	if !ok {
+		return false
+	}
+
+	// 验证码不保留在聊天记录中（群组中需要Bot有删除消息权限）
+	if _, err := b.messenger.Request(tgbotapi.NewDeleteMessage(chatID, message.MessageID)); err != nil {
+		log.Printf("删除验证码消息失败: ChatID=%d, %v", chatID, err)
+	}
+
+	result := b.auth.Verify(userID, message.Text, now)
+	switch {
+	case result.OK:
+		b.messenger.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ 验证成功，%s内无需再次验证", formatDuration(b.auth.session))))
+		if resume != nil {
+			resume(ctx)
+		}
+	case !result.LockedUntil.IsZero():
+		b.messenger.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("⛔ 验证已锁定，请于 %s 后重试", result.LockedUntil.Format("15:04"))))
+	default:
+		b.auth.SetPending(chatID, userID, resume, now)
+		b.messenger.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ 验证码错误，还可尝试 %d 次", result.Remaining)))
+	}
+	return true
+}
@@ -0,0 +1,143 @@
+package bot
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"teslamate-bot/config"
+)
+
+// rfc6238Secret RFC 6238 附录 B 中 SHA1 测试向量使用的密钥
+const rfc6238Secret = "12345678901234567890"
+
+// newTestAuth 用户 1 使用 RFC 6238 测试密钥，用户 2 使用 PIN，连续失败 3 次锁定 15 分钟
+func newTestAuth(t *testing.T, path string) *Auth {
+	cfg := config.AuthConfig{
+		SessionMinutes: 15,
+		MaxFailures:    3,
+		LockoutMinutes: 15,
+		Sensitive:      []string{"control"},
+		Users: []config.AuthUser{
+			{UserID: 1, TOTPSecret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"},
+			{UserID: 2, PIN: "2468"},
+		},
+	}
+	return NewAuth(cfg, openTestStore(t, path))
+}
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 附录 B 的 8 位验证码取后 6 位
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		if got := totpCode([]byte(rfc6238Secret), tt.unix/totpStep); got != tt.want {
+			t.Errorf("totpCode(T=%d) = %s, 期望 %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyTOTPWindow(t *testing.T) {
+	secret := []byte(rfc6238Secret)
+	now := time.Unix(1234567890, 0)
+	counter := now.Unix() / totpStep
+
+	for offset := int64(-2); offset <= 2; offset++ {
+		got, ok := verifyTOTP(secret, totpCode(secret, counter+offset), now)
+		want := offset >= -totpWindow && offset <= totpWindow
+		if ok != want || (ok && got != counter+offset) {
+			t.Errorf("偏差 %d 步: verifyTOTP = %d, %v, 期望通过 = %v", offset, got, ok, want)
+		}
+	}
+	for _, code := range []string{"", "00592", "0059240", "abcdef"} {
+		if _, ok := verifyTOTP(secret, code, now); ok {
+			t.Errorf("verifyTOTP(%q) 通过", code)
+		}
+	}
+}
+
+func TestAuthTOTPReplay(t *testing.T) {
+	a := newTestAuth(t, filepath.Join(t.TempDir(), "teslamate-bot.db"))
+	secret := []byte(rfc6238Secret)
+	now := time.Unix(1234567890, 0)
+	counter := now.Unix() / totpStep
+
+	if !a.Verify(1, totpCode(secret, counter), now).OK {
+		t.Fatal("正确的验证码未通过")
+	}
+	// 已使用的验证码及更早的验证码不能再次使用
+	if a.Verify(1, totpCode(secret, counter), now.Add(time.Second)).OK {
+		t.Error("重放的验证码通过")
+	}
+	if a.Verify(1, totpCode(secret, counter-1), now.Add(time.Second)).OK {
+		t.Error("更早的验证码通过")
+	}
+	if !a.Verify(1, totpCode(secret, counter+1), now.Add(totpStep*time.Second)).OK {
+		t.Error("下一个时间步的验证码未通过")
+	}
+}
+
+func TestAuthLockout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "teslamate-bot.db")
+	a := newTestAuth(t, path)
+	now := time.Unix(1700000000, 0)
+
+	for i, want := range []int{2, 1} {
+		if r := a.Verify(2, "0000", now); r.OK || r.Remaining != want {
+			t.Fatalf("第 %d 次失败: %+v, 期望剩余 %d 次", i+1, r, want)
+		}
+	}
+	r := a.Verify(2, "0000", now)
+	if want := now.Add(15 * time.Minute); !r.LockedUntil.Equal(want) {
+		t.Fatalf("第 3 次失败: %+v, 期望锁定至 %s", r, want)
+	}
+
+	// 锁定期间正确的 PIN 也不通过，重启后仍然锁定
+	if a.Verify(2, "2468", now.Add(time.Minute)).OK {
+		t.Error("锁定期间验证通过")
+	}
+	a.store.Close()
+	a = newTestAuth(t, path)
+	if r := a.Verify(2, "2468", now.Add(14*time.Minute)); r.OK || r.LockedUntil.IsZero() {
+		t.Errorf("重启后验证结果 = %+v, 期望仍然锁定", r)
+	}
+	if !a.Required(2, "control", now.Add(14*time.Minute)) {
+		t.Error("重启后保留了验证有效期")
+	}
+
+	// 锁定结束后可以验证，成功后失败次数清零
+	if !a.Verify(2, "2468", now.Add(15*time.Minute)).OK {
+		t.Fatal("锁定结束后验证未通过")
+	}
+	if a.Required(2, "control", now.Add(16*time.Minute)) {
+		t.Error("验证有效期内仍需要验证")
+	}
+	if r := a.Verify(2, "0000", now.Add(16*time.Minute)); r.Remaining != 2 {
+		t.Errorf("验证成功后失败: %+v, 期望剩余 2 次", r)
+	}
+}
+
+func TestAuthTOTPReplayAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "teslamate-bot.db")
+	a := newTestAuth(t, path)
+	secret := []byte(rfc6238Secret)
+	now := time.Unix(1234567890, 0)
+	code := totpCode(secret, now.Unix()/totpStep)
+
+	if !a.Verify(1, code, now).OK {
+		t.Fatal("正确的验证码未通过")
+	}
+	a.store.Close()
+	if newTestAuth(t, path).Verify(1, code, now.Add(time.Second)).OK {
+		t.Error("重启后重放的验证码通过")
+	}
+}
@@ -1,24 +1,67 @@
 package bot
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"teslamate-bot/client"
+	"teslamate-bot/config"
+	"teslamate-bot/models"
+	"teslamate-bot/store"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+const (
+	// shutdownTimeout 退出时等待处理中的更新、轮询和定时任务完成的时长
+	shutdownTimeout = 20 * time.Second
+	// shutdownGrace 取消进行中的请求后再等待的时长
+	shutdownGrace = 5 * time.Second
+	// pollTimeout 长轮询超时（秒），退出时需等待进行中的长轮询返回后更新通道才会关闭
+	pollTimeout = 10
+)
+
+// ErrShutdownTimeout 取消进行中的请求后仍有任务未结束，这些任务可能还在读写状态存储
+var ErrShutdownTimeout = errors.New("仍有任务未结束，强制退出")
+
 // Bot Telegram Bot结构
 type Bot struct {
-	api              *tgbotapi.BotAPI
-	handler          *Handler
-	whitelistChatIDs map[int64]bool
+	api          *tgbotapi.BotAPI // 接收更新（Webhook 或长轮询）
+	messenger    Messenger        // 发送消息
+	tmClient     client.TeslaMateAPI
+	username     string
+	handler      *Handler
+	access       *Access
+	defaultRole  Role
+	accessAlerts accessAlerts
+	confirms     controlConfirms
+	invites      *Invites
+	auth         *Auth
+	telegram     config.TelegramConfig
+	webhook      *webhookServer
+	prefs        *Preferences
+	alerts       *Alerts
+	cars         []models.Car
+	defaultCarID int
+	defaultCars  map[int64]int
+	drains       map[int]*DrainTracker
+	geofences    map[int]*GeofenceTracker
+	monitor      *Monitor
+	scheduler    *Scheduler
 }
 
 // NewBot 创建新的Bot实例
-func NewBot(token string, whitelistChatIDs []int64, apiEndpoint string, tmClient *client.Client) (*Bot, error) {
+func NewBot(ctx context.Context, cfg *config.Config, tmClient *client.Client, st *store.Store) (*Bot, error) {
+	token := cfg.Telegram.BotToken
+	apiEndpoint := cfg.Telegram.APIEndpoint
+
 	var botAPI *tgbotapi.BotAPI
 	var err error
 
@@ -39,19 +82,126 @@ func NewBot(token string, whitelistChatIDs []int64, apiEndpoint string, tmClient
 		log.Println("使用默认Telegram API")
 	}
 
-	// 创建白名单映射
-	whitelist := make(map[int64]bool)
-	for _, chatID := range whitelistChatIDs {
-		whitelist[chatID] = true
+	log.Printf("已授权使用 Bot: %s", botAPI.Self.UserName)
+
+	b, err := newBot(ctx, cfg, botAPI, botAPI.Self.UserName, tmClient, st)
+	if err != nil {
+		return nil, err
+	}
+	b.api = botAPI
+	tmClient.OnAvailabilityChange(b.notifyAvailability)
+	return b, nil
+}
+
+// newBot 创建Bot（不含接收更新的部分），消息通过 messenger 发送
+func newBot(ctx context.Context, cfg *config.Config, messenger Messenger, username string, tmClient client.TeslaMateAPI, st *store.Store) (*Bot, error) {
+	cars, err := discoverCars(ctx, tmClient, cfg.TeslaMate.CarID)
+	if err != nil {
+		return nil, err
 	}
 
-	log.Printf("已授权使用 Bot: %s", botAPI.Self.UserName)
+	b := &Bot{
+		messenger:    messenger,
+		tmClient:     tmClient,
+		username:     username,
+		handler:      NewHandler(tmClient),
+		access:       NewAccess(cfg.Telegram, st),
+		defaultRole:  parseRole(cfg.Telegram.DefaultRole),
+		invites:      NewInvites(st),
+		auth:         NewAuth(cfg.Auth, st),
+		telegram:     cfg.Telegram,
+		prefs:        NewPreferences(st),
+		alerts:       NewAlerts(st),
+		cars:         cars,
+		defaultCarID: cars[0].CarID,
+		defaultCars:  cfg.Telegram.DefaultCarIDs,
+		drains:       make(map[int]*DrainTracker),
+		geofences:    make(map[int]*GeofenceTracker),
+	}
+	if cfg.TeslaMate.CarID > 0 {
+		b.defaultCarID = cfg.TeslaMate.CarID
+	}
+	for chatID, carID := range b.defaultCars {
+		if !b.hasCar(carID) {
+			return nil, fmt.Errorf("会话 %d 的默认车辆 %d 不存在", chatID, carID)
+		}
+	}
+
+	loc, err := cfg.Scheduler.Location()
+	if err != nil {
+		return nil, err
+	}
+	for _, car := range cars {
+		b.drains[car.CarID] = NewDrainTracker(car.CarID, cfg.Monitor.Drain, loc, tmClient, st)
+		b.geofences[car.CarID] = NewGeofenceTracker(car.CarID, cfg.Monitor.Geofences, st)
+	}
+
+	// 后台监控的车辆（未配置 car_ids 时监控全部车辆）
+	monitored := cars
+	if len(cfg.TeslaMate.CarIDs) > 0 {
+		monitored = nil
+		for _, carID := range cfg.TeslaMate.CarIDs {
+			car, ok := b.findCar(carID)
+			if !ok {
+				return nil, fmt.Errorf("car_ids 中的车辆 %d 不存在", carID)
+			}
+			monitored = append(monitored, car)
+		}
+	}
+
+	if cfg.Monitor.Enabled {
+		b.monitor = NewMonitor(tmClient, time.Duration(cfg.Monitor.Interval)*time.Second, b.broadcast)
+		for _, car := range monitored {
+			id := car.CarID
+			watchers := []Watcher{
+				newChargingWatcher(id, st),
+				newDriveWatcher(id, tmClient, st),
+				newSecurityWatcher(id, cfg.Monitor.Security, st),
+				newTiresWatcher(id, cfg.Monitor.Tires, st),
+				newVersionWatcher(id, st),
+				&alertsWatcher{carID: id, alerts: b.alerts},
+				b.drains[id],
+				b.geofences[id],
+				newStateWatcher(id, cfg.Monitor.State, st),
+			}
+			if cfg.Storage.SnapshotMinutes > 0 {
+				watchers = append(watchers, newSnapshotWatcher(id, cfg.Storage, st))
+			}
+			b.monitor.AddCar(id, watchers...)
+		}
+	}
+
+	if len(cfg.Scheduler.Digests) > 0 {
+		b.scheduler, err = NewScheduler(cfg.Scheduler, tmClient, monitored, b.broadcast)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+// discoverCars 从 TeslaMate 获取车辆列表（获取失败且配置了 car_id 时仅使用该车辆）
+func discoverCars(ctx context.Context, tmClient client.TeslaMateAPI, defaultCarID int) ([]models.Car, error) {
+	cars, err := tmClient.GetCars(ctx)
+	if err != nil {
+		if defaultCarID > 0 {
+			log.Printf("%v，仅使用配置的车辆 (CarID: %d)", err, defaultCarID)
+			return []models.Car{{CarID: defaultCarID}}, nil
+		}
+		return nil, err
+	}
+	if len(cars) == 0 {
+		return nil, fmt.Errorf("TeslaMate 中没有车辆")
+	}
+	if defaultCarID > 0 && !slices.ContainsFunc(cars, func(car models.Car) bool { return car.CarID == defaultCarID }) {
+		return nil, fmt.Errorf("配置的默认车辆 %d 不存在", defaultCarID)
+	}
 
-	return &Bot{
-		api:              botAPI,
-		handler:          NewHandler(tmClient),
-		whitelistChatIDs: whitelist,
-	}, nil
+	for _, car := range cars {
+		log.Printf("发现车辆: #%d %s", car.CarID, carName(car))
+	}
+	return cars, nil
 }
 
 // registerCommands 向 Telegram 注册 Bot 指令（用于输入框旁的命令列表）
@@ -64,261 +214,702 @@ func (b *Bot) registerCommands() error {
 		tgbotapi.BotCommand{Command: "battery", Description: "电池健康"},
 		tgbotapi.BotCommand{Command: "charge", Description: "最新充电"},
 		tgbotapi.BotCommand{Command: "drive", Description: "最近驾驶"},
+		tgbotapi.BotCommand{Command: "tires", Description: "胎压"},
+		tgbotapi.BotCommand{Command: "version", Description: "软件版本"},
+		tgbotapi.BotCommand{Command: "drain", Description: "停车掉电"},
+		tgbotapi.BotCommand{Command: "location", Description: "车辆位置"},
+		tgbotapi.BotCommand{Command: "notify", Description: "推送设置"},
+		tgbotapi.BotCommand{Command: "alerts", Description: "电量提醒"},
+		tgbotapi.BotCommand{Command: "geofences", Description: "围栏通知"},
+		tgbotapi.BotCommand{Command: "control", Description: "远程控制"},
+		tgbotapi.BotCommand{Command: "cars", Description: "切换车辆"},
+		tgbotapi.BotCommand{Command: "auth", Description: "二次验证"},
+		tgbotapi.BotCommand{Command: "lock", Description: "结束验证有效期"},
+		tgbotapi.BotCommand{Command: "users", Description: "授权列表（管理员）"},
+		tgbotapi.BotCommand{Command: "invite", Description: "生成邀请码（管理员）"},
 	)
-	_, err := b.api.Request(cfg)
+	_, err := b.messenger.Request(cfg)
 	return err
 }
 
-// Start 启动Bot
-func (b *Bot) Start() error {
+// Start 启动Bot，阻塞直到 ctx 被取消并完成退出
+//
+// 退出时先停止接收更新和后台监控，等待处理中的更新、轮询和定时任务完成，
+// 超过 shutdownTimeout 后取消进行中的请求，之后仍未结束时返回 ErrShutdownTimeout。
+func (b *Bot) Start(ctx context.Context) error {
 	if err := b.registerCommands(); err != nil {
 		log.Printf("注册 Telegram 指令失败（不影响运行）: %v", err)
 	} else {
 		log.Println("已注册 Telegram 指令")
 	}
+
+	updates, err := b.receive()
+	if err != nil {
+		return err
+	}
 	log.Println("开始接收消息...")
 
+	// 进行中的工作使用独立的 context，收到退出信号后仍可在截止时间前完成
+	work, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+
+	var wg sync.WaitGroup
+	stopMonitor := make(chan struct{})
+	if b.monitor != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.monitor.Run(work, stopMonitor)
+		}()
+	}
+	if b.scheduler != nil {
+		b.scheduler.Start(work)
+	}
+
+	// 由工作池并发处理更新。收到退出信号后先停止接收，已接收的更新继续提交，
+	// 直到更新通道关闭（或取消进行中的工作），再等待已提交的更新处理完成
+	pool := newUpdatePool(b.telegram.Workers, func(update tgbotapi.Update) {
+		b.handleUpdate(work, update)
+	})
+	log.Printf("更新处理工作协程: %d", b.telegram.Workers)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer pool.Close()
+		for {
+			select {
+			case <-work.Done():
+				return
+			case update, ok := <-updates:
+				if !ok {
+					return
+				}
+				pool.Submit(update)
+			}
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("正在停止接收更新...")
+	b.stopReceiving()
+	close(stopMonitor)
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		if b.scheduler != nil {
+			<-b.scheduler.Stop()
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Println("处理中的任务已完成")
+	case <-time.After(shutdownTimeout):
+		log.Printf("等待处理中的任务超过 %s（%d 条更新待处理），取消进行中的请求", shutdownTimeout, pool.Depth())
+		cancelWork()
+		b.tmClient.Close()
+		select {
+		case <-done:
+		case <-time.After(shutdownGrace):
+			return ErrShutdownTimeout
+		}
+	}
+	return nil
+}
+
+// handleUpdate 处理一条更新
+func (b *Bot) handleUpdate(ctx context.Context, update tgbotapi.Update) {
+	// 处理消息
+	if update.Message != nil {
+		b.handleMessage(ctx, update.Message)
+	}
+
+	// 处理回调查询
+	if update.CallbackQuery != nil {
+		b.handleCallbackQuery(ctx, update.CallbackQuery)
+	}
+}
+
+// receive 按配置的方式开始接收更新
+func (b *Bot) receive() (tgbotapi.UpdatesChannel, error) {
+	if b.telegram.Mode == "webhook" {
+		return b.startWebhook(b.telegram.Webhook)
+	}
+
+	// 长轮询与 Webhook 互斥，之前设置过的 Webhook 需要先删除
+	if _, err := b.api.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		log.Printf("删除 Webhook 失败: %v", err)
+	}
+
 	// 配置更新
 	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+	u.Timeout = pollTimeout
 
 	// 获取更新通道
-	updates := b.api.GetUpdatesChan(u)
+	return b.api.GetUpdatesChan(u), nil
+}
 
-	// 处理更新
-	for update := range updates {
-		// 处理消息
-		if update.Message != nil {
-			b.handleMessage(update.Message)
-		}
+// stopReceiving 停止接收更新（Webhook 模式下同时删除 Webhook）
+func (b *Bot) stopReceiving() {
+	if b.webhook != nil {
+		b.stopWebhook()
+		return
+	}
+	b.api.StopReceivingUpdates()
+}
 
-		// 处理回调查询
-		if update.CallbackQuery != nil {
-			b.handleCallbackQuery(update.CallbackQuery)
-		}
+// isInviteStart 判断消息是否为 /start <邀请码>
+func isInviteStart(message *tgbotapi.Message) bool {
+	return message.IsCommand() && message.Command() == "start" && message.CommandArguments() != ""
+}
+
+// senderID 消息发送者的用户ID（频道消息等没有发送者时为0）
+func senderID(user *tgbotapi.User) int64 {
+	if user == nil {
+		return 0
 	}
+	return user.ID
+}
 
-	return nil
+// deny 回复权限不足
+func (b *Bot) deny(chatID int64, required, current Role) {
+	b.messenger.Send(tgbotapi.NewMessage(chatID, b.handler.HandleDenied(required, current)))
+}
+
+// broadcast 向订阅了该主题的白名单会话推送消息（指定了会话时只推送给该会话）
+func (b *Bot) broadcast(n Notification) {
+	for _, chatID := range b.access.ChatIDs() {
+		if n.ChatID != 0 && n.ChatID != chatID {
+			continue
+		}
+		if n.ChatID == 0 && !b.prefs.Enabled(chatID, n.Topic) {
+			continue
+		}
+		msg := tgbotapi.NewMessage(chatID, n.Text)
+		if _, err := b.messenger.Send(msg); err != nil {
+			log.Printf("推送消息失败: ChatID=%d, %v", chatID, err)
+		}
+	}
 }
 
-// isAuthorized 检查用户是否在白名单中
-func (b *Bot) isAuthorized(chatID int64) bool {
-	return b.whitelistChatIDs[chatID]
+// notifyAvailability 推送 TeslaMate API 熔断与恢复通知
+func (b *Bot) notifyAvailability(available bool) {
+	text := "⚠️ TeslaMate API 暂不可用\n━━━━━━━━━━━━━━━━━━━━\n连续请求失败，已暂停访问，稍后自动重试"
+	if available {
+		text = "✅ TeslaMate API 已恢复\n━━━━━━━━━━━━━━━━━━━━\n查询与推送恢复正常"
+	}
+	b.broadcast(Notification{Topic: "api", Text: text})
 }
 
 // handleMessage 处理文本消息
-func (b *Bot) handleMessage(message *tgbotapi.Message) {
-	// 检查白名单
-	if !b.isAuthorized(message.Chat.ID) {
-		log.Printf("未授权访问尝试: ChatID=%d, User=%s", message.Chat.ID, message.From.UserName)
-		// 不做任何响应，直接返回
+func (b *Bot) handleMessage(ctx context.Context, message *tgbotapi.Message) {
+	// 检查白名单（/start <邀请码> 交给 handleCommand 处理）
+	if b.access.Role(message.Chat.ID, senderID(message.From)) == RoleNone && !isInviteStart(message) {
+		log.Printf("未授权访问尝试: ChatID=%d, UserID=%d", message.Chat.ID, senderID(message.From))
+		// 不响应对方，只提醒管理员
+		b.notifyUnknownAccess(message.Chat, message.From, message.Text)
 		return
 	}
 
 	// 处理命令
 	if message.IsCommand() {
-		b.handleCommand(message)
+		b.handleCommand(ctx, message)
 		return
 	}
+
+	// 等待二次验证时，文本消息视为验证码
+	if b.auth.Enabled(senderID(message.From)) {
+		b.handleAuthCode(ctx, message)
+	}
 }
 
 // handleCommand 处理命令
-func (b *Bot) handleCommand(message *tgbotapi.Message) {
+func (b *Bot) handleCommand(ctx context.Context, message *tgbotapi.Message) {
 	command := message.Command()
 	chatID := message.Chat.ID
+	userID := senderID(message.From)
 
-	log.Printf("收到命令: %s, ChatID=%d", command, chatID)
+	log.Printf("收到命令: %s, ChatID=%d, UserID=%d", command, chatID, userID)
+
+	// 检查角色权限
+	role := b.access.Role(chatID, userID)
+	if role == RoleNone {
+		// 未授权会话只能使用邀请码
+		if isInviteStart(message) {
+			b.redeemInvite(message, message.CommandArguments())
+		}
+		return
+	}
+	if required := commandRole(command, message.CommandArguments()); role < required {
+		log.Printf("权限不足: %s, ChatID=%d, UserID=%d, 角色=%s", command, chatID, userID, role)
+		b.deny(chatID, required, role)
+		return
+	}
+
+	// 敏感功能需要二次验证
+	if b.auth.Required(userID, command, time.Now()) {
+		b.requestAuth(message.Chat, userID, func(ctx context.Context) { b.handleCommand(ctx, message) })
+		return
+	}
 
 	switch command {
 	case "start":
-		text := b.handler.HandleStart()
-		msg := tgbotapi.NewMessage(chatID, text)
-		msg.ReplyMarkup = GetMainMenu()
-		b.api.Send(msg)
+		b.sendMainMenu(chatID)
 
 	case "help":
 		text := b.handler.HandleHelp()
 		msg := tgbotapi.NewMessage(chatID, text)
-		b.api.Send(msg)
+		b.messenger.Send(msg)
 
-	case "info":
-		b.sendInfo(chatID)
+	case "info", "status", "battery", "charge", "drive", "tires", "version", "drain", "location":
+		b.sendView(ctx, chatID, command, b.carFor(chatID))
 
-	case "status":
-		b.sendStatus(chatID)
+	case "control":
+		b.sendControl(chatID, b.carFor(chatID))
 
-	case "battery":
-		b.sendBattery(chatID)
+	case "cars":
+		b.sendCars(chatID)
 
-	case "charge":
-		b.sendCharge(chatID)
+	case "notify":
+		b.sendNotify(chatID)
 
-	case "drive":
-		b.sendDrive(chatID)
+	case "users":
+		b.sendUsers(chatID)
+
+	case "allow":
+		b.handleAllowCommand(chatID, message.CommandArguments())
+
+	case "deny":
+		b.handleDenyCommand(chatID, userID, message.CommandArguments())
+
+	case "invite":
+		b.handleInviteCommand(chatID, message.CommandArguments())
+
+	case "auth":
+		if !b.auth.Enabled(userID) {
+			b.messenger.Send(tgbotapi.NewMessage(chatID, "❓ 您未配置二次验证"))
+			break
+		}
+		b.requestAuth(message.Chat, userID, nil)
+
+	case "lock":
+		b.auth.Lock(userID)
+		b.messenger.Send(tgbotapi.NewMessage(chatID, "🔒 已结束二次验证有效期"))
+
+	case "alerts":
+		b.handleAlertsCommand(chatID, message.CommandArguments())
+
+	case "geofences":
+		b.sendGeofences(chatID)
 
 	default:
 		msg := tgbotapi.NewMessage(chatID, "❓ 未知命令，请使用 /help 查看可用命令")
-		b.api.Send(msg)
+		b.messenger.Send(msg)
 	}
 }
 
 // handleCallbackQuery 处理回调查询
-func (b *Bot) handleCallbackQuery(query *tgbotapi.CallbackQuery) {
+func (b *Bot) handleCallbackQuery(ctx context.Context, query *tgbotapi.CallbackQuery) {
+	data := query.Data
+	chatID := query.Message.Chat.ID
+	messageID := query.Message.MessageID
+	userID := senderID(query.From)
+
 	// 检查白名单
-	if !b.isAuthorized(query.Message.Chat.ID) {
+	role := b.access.Role(chatID, userID)
+	if role == RoleNone {
 		// 不做任何响应，直接返回
 		return
 	}
 
-	data := query.Data
-	chatID := query.Message.Chat.ID
-	messageID := query.Message.MessageID
+	log.Printf("收到回调: %s, ChatID=%d, UserID=%d", data, chatID, userID)
+
+	// 检查角色权限（群组中按点击按钮的用户判断）
+	if required := callbackRole(data); role < required {
+		log.Printf("权限不足: %s, ChatID=%d, UserID=%d, 角色=%s", data, chatID, userID, role)
+		b.messenger.Request(tgbotapi.NewCallbackWithAlert(query.ID, b.handler.HandleDenied(required, role)))
+		return
+	}
+
+	// 敏感功能需要二次验证，验证通过后重新处理该回调
+	if b.auth.Required(userID, callbackFeature(data), time.Now()) {
+		b.messenger.Request(tgbotapi.NewCallback(query.ID, "🔐 需要二次验证"))
+		b.requestAuth(query.Message.Chat, userID, func(ctx context.Context) { b.handleCallbackQuery(ctx, query) })
+		return
+	}
 
-	log.Printf("收到回调: %s, ChatID=%d", data, chatID)
+	// 带车辆ID的回调（如 status:2、refresh_status:2、select_car:2）
+	if action, carID, ok := parseCarData(data); ok {
+		if !b.hasCar(carID) {
+			b.messenger.Request(tgbotapi.NewCallback(query.ID, fmt.Sprintf("❓ 车辆 #%d 不存在", carID)))
+			return
+		}
+		// 重复点击确认按钮时不重复执行远程操作
+		if strings.HasPrefix(action, "ctlok_") && !b.confirms.claim(chatID, messageID, time.Now()) {
+			b.messenger.Request(tgbotapi.NewCallback(query.ID, "⏳ 已在执行"))
+			return
+		}
+		b.messenger.Request(tgbotapi.NewCallback(query.ID, ""))
+		b.handleCarCallback(ctx, chatID, messageID, action, carID)
+		return
+	}
 
 	// 先回应回调查询
 	callback := tgbotapi.NewCallback(query.ID, "")
-	b.api.Request(callback)
+	b.messenger.Request(callback)
 
 	// 处理不同的回调
 	switch {
-	case data == "info":
-		b.sendInfo(chatID)
+	case isCarView(data):
+		// 兼容不带车辆ID的旧按钮
+		b.sendView(ctx, chatID, data, b.carFor(chatID))
+
+	case data == "cars":
+		b.sendCars(chatID)
+
+	case data == "notify":
+		b.sendNotify(chatID)
+
+	case strings.HasPrefix(data, "notify_"):
+		topic := strings.TrimPrefix(data, "notify_")
+		if _, ok := findTopic(topic); !ok {
+			break
+		}
+		b.prefs.Toggle(chatID, topic)
+		menu := GetNotifyMenu(chatID, b.prefs)
+		b.messenger.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, menu))
 
-	case data == "status":
-		b.sendStatus(chatID)
+	case data == "alerts":
+		b.sendAlerts(chatID)
 
-	case data == "battery":
-		b.sendBattery(chatID)
+	case strings.HasPrefix(data, "alerts_"):
+		b.handleAlertsCallback(chatID, messageID, strings.TrimPrefix(data, "alerts_"))
 
-	case data == "charge":
-		b.sendCharge(chatID)
+	case data == "geofences":
+		b.sendGeofences(chatID)
 
-	case data == "drive":
-		b.sendDrive(chatID)
+	case strings.HasPrefix(data, "geofence_"):
+		b.handleGeofenceToggle(chatID, messageID, strings.TrimPrefix(data, "geofence_"))
 
 	case data == "back_main":
-		text := b.handler.HandleStart()
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
-		menu := GetMainMenu()
+		edit := tgbotapi.NewEditMessageText(chatID, messageID, b.mainMenuText(chatID))
+		menu := GetMainMenu(b.carFor(chatID), len(b.cars) > 1)
 		edit.ReplyMarkup = &menu
-		b.api.Send(edit)
+		b.messenger.Send(edit)
 
-	case strings.HasPrefix(data, "refresh_"):
-		refreshType := strings.TrimPrefix(data, "refresh_")
-		b.handleRefresh(chatID, messageID, refreshType)
+	case strings.HasPrefix(data, "refresh_") && isCarView(strings.TrimPrefix(data, "refresh_")):
+		b.refreshView(ctx, chatID, messageID, strings.TrimPrefix(data, "refresh_"), b.carFor(chatID))
 
 	default:
-		b.api.Request(tgbotapi.NewCallback(query.ID, "❓ 未知操作"))
+		b.messenger.Request(tgbotapi.NewCallback(query.ID, "❓ 未知操作"))
 	}
 }
 
-// handleRefresh 处理刷新操作
-func (b *Bot) handleRefresh(chatID int64, messageID int, refreshType string) {
-	switch refreshType {
-	case "info":
-		text, err := b.handler.HandleInfo()
-		if err != nil {
-			text = fmt.Sprintf("❌ 获取车辆信息失败: %v", err)
-		}
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
-		menu := GetRefreshMenu("info")
-		edit.ReplyMarkup = &menu
-		b.api.Send(edit)
+// handleCarCallback 处理带车辆ID的回调
+func (b *Bot) handleCarCallback(ctx context.Context, chatID int64, messageID int, action string, carID int) {
+	switch {
+	case isCarView(action):
+		b.sendView(ctx, chatID, action, carID)
 
-	case "status":
-		text, err := b.handler.HandleStatus()
-		if err != nil {
-			text = fmt.Sprintf("❌ 获取车辆状态失败: %v", err)
-		}
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
-		menu := GetRefreshMenu("status")
-		edit.ReplyMarkup = &menu
-		b.api.Send(edit)
+	case strings.HasPrefix(action, "refresh_") && isCarView(strings.TrimPrefix(action, "refresh_")):
+		b.refreshView(ctx, chatID, messageID, strings.TrimPrefix(action, "refresh_"), carID)
 
-	case "battery":
-		text, err := b.handler.HandleBattery()
-		if err != nil {
-			text = fmt.Sprintf("❌ 获取电池健康度失败: %v", err)
-		}
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
-		menu := GetRefreshMenu("battery")
+	case action == "control":
+		car, _ := b.findCar(carID)
+		edit := tgbotapi.NewEditMessageText(chatID, messageID, b.handler.HandleControl(car))
+		menu := GetControlMenu(carID)
 		edit.ReplyMarkup = &menu
-		b.api.Send(edit)
+		b.messenger.Send(edit)
 
-	case "charge":
-		text, err := b.handler.HandleCharge()
-		if err != nil {
-			text = fmt.Sprintf("❌ 获取充电记录失败: %v", err)
-		}
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
-		menu := GetRefreshMenu("charge")
-		edit.ReplyMarkup = &menu
-		b.api.Send(edit)
+	case strings.HasPrefix(action, "ctl_"):
+		b.confirmControl(chatID, messageID, strings.TrimPrefix(action, "ctl_"), carID)
 
-	case "drive":
-		text, err := b.handler.HandleDrive()
-		if err != nil {
-			text = fmt.Sprintf("❌ 获取驾驶信息失败: %v", err)
-		}
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
-		menu := GetRefreshMenu("drive")
+	case strings.HasPrefix(action, "ctlok_"):
+		b.executeControl(ctx, chatID, messageID, strings.TrimPrefix(action, "ctlok_"), carID)
+
+	case action == "select_car":
+		b.prefs.SetActiveCar(chatID, carID)
+		edit := tgbotapi.NewEditMessageText(chatID, messageID, b.handler.HandleCars(b.cars, carID))
+		menu := GetCarsMenu(b.cars, carID)
 		edit.ReplyMarkup = &menu
-		b.api.Send(edit)
+		b.messenger.Send(edit)
 	}
 }
 
-// sendInfo 发送车辆信息
-func (b *Bot) sendInfo(chatID int64) {
-	text, err := b.handler.HandleInfo()
+// parseCarData 解析带车辆ID的回调数据（如 status:2）
+func parseCarData(data string) (string, int, bool) {
+	action, id, ok := strings.Cut(data, ":")
+	if !ok {
+		return "", 0, false
+	}
+	carID, err := strconv.Atoi(id)
 	if err != nil {
-		text = fmt.Sprintf("❌ 获取车辆信息失败: %v", err)
+		return "", 0, false
 	}
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ReplyMarkup = GetRefreshMenu("info")
-	b.api.Send(msg)
+	return action, carID, true
+}
+
+// carViews 与车辆相关的查看页面（命令名与回调数据相同）
+var carViews = []string{"info", "status", "battery", "charge", "drive", "tires", "version", "drain", "location"}
+
+// isCarView 判断是否为车辆查看页面
+func isCarView(view string) bool {
+	return slices.Contains(carViews, view)
 }
 
-// sendStatus 发送车辆状态
-func (b *Bot) sendStatus(chatID int64) {
-	text, err := b.handler.HandleStatus()
+// renderView 生成车辆查看页面的内容（refresh 为 true 时忽略缓存重新获取）
+func (b *Bot) renderView(ctx context.Context, view string, carID int, refresh bool) string {
+	if refresh {
+		ctx = client.WithoutCache(ctx)
+	}
+	ctx, cache := client.WithCacheInfo(ctx)
+
+	var text string
+	var err error
+	var failure string
+
+	switch view {
+	case "info":
+		text, err = b.handler.HandleInfo(ctx, carID)
+		failure = "获取车辆信息失败"
+	case "status":
+		text, err = b.handler.HandleStatus(ctx, carID)
+		failure = "获取车辆状态失败"
+	case "battery":
+		text, err = b.handler.HandleBattery(ctx, carID)
+		failure = "获取电池健康度失败"
+	case "charge":
+		text, err = b.handler.HandleCharge(ctx, carID)
+		failure = "获取充电记录失败"
+	case "drive":
+		text, err = b.handler.HandleDrive(ctx, carID)
+		failure = "获取驾驶信息失败"
+	case "tires":
+		text, err = b.handler.HandleTires(ctx, carID)
+		failure = "获取胎压失败"
+	case "version":
+		text, err = b.handler.HandleVersion(ctx, carID)
+		failure = "获取软件版本失败"
+	case "drain":
+		text = b.handler.HandleDrain(ctx, b.drains[carID])
+	case "location":
+		text, err = b.handler.HandleLocation(ctx, carID, b.geofences[carID])
+		failure = "获取车辆位置失败"
+	}
+
 	if err != nil {
-		text = fmt.Sprintf("❌ 获取车辆状态失败: %v", err)
+		log.Printf("%s (CarID: %d): %v", failure, carID, err)
+		return fmt.Sprintf("❌ %s: %s", failure, errorText(err))
+	}
+	if age := cache.Age(time.Now()); age >= time.Second {
+		text += fmt.Sprintf("\n\n🕒 数据来自 %d 秒前", int(age.Seconds()))
 	}
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ReplyMarkup = GetRefreshMenu("status")
-	b.api.Send(msg)
+	return text
 }
 
-// sendBattery 发送电池健康度
-func (b *Bot) sendBattery(chatID int64) {
-	text, err := b.handler.HandleBattery()
-	if err != nil {
-		text = fmt.Sprintf("❌ 获取电池健康度失败: %v", err)
+// sendView 发送车辆查看页面
+func (b *Bot) sendView(ctx context.Context, chatID int64, view string, carID int) {
+	msg := tgbotapi.NewMessage(chatID, b.renderView(ctx, view, carID, false))
+	msg.ReplyMarkup = GetRefreshMenu(view, carID)
+	msg.DisableWebPagePreview = view == "location"
+	b.messenger.Send(msg)
+}
+
+// refreshView 刷新车辆查看页面
+func (b *Bot) refreshView(ctx context.Context, chatID int64, messageID int, view string, carID int) {
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, b.renderView(ctx, view, carID, true))
+	menu := GetRefreshMenu(view, carID)
+	edit.ReplyMarkup = &menu
+	edit.DisableWebPagePreview = view == "location"
+	b.messenger.Send(edit)
+}
+
+// mainMenuText 主菜单文字（多车时显示当前车辆）
+func (b *Bot) mainMenuText(chatID int64) string {
+	text := b.handler.HandleStart()
+	if len(b.cars) > 1 {
+		car, _ := b.findCar(b.carFor(chatID))
+		text += fmt.Sprintf("\n\n🚘 当前车辆: %s（/cars 切换）", carName(car))
 	}
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ReplyMarkup = GetRefreshMenu("battery")
-	b.api.Send(msg)
+	return text
 }
 
-// sendCharge 发送最新充电记录
-func (b *Bot) sendCharge(chatID int64) {
-	text, err := b.handler.HandleCharge()
-	if err != nil {
-		text = fmt.Sprintf("❌ 获取充电记录失败: %v", err)
+// sendMainMenu 发送主菜单
+func (b *Bot) sendMainMenu(chatID int64) {
+	msg := tgbotapi.NewMessage(chatID, b.mainMenuText(chatID))
+	msg.ReplyMarkup = GetMainMenu(b.carFor(chatID), len(b.cars) > 1)
+	b.messenger.Send(msg)
+}
+
+// sendCars 发送车辆选择菜单
+func (b *Bot) sendCars(chatID int64) {
+	carID := b.carFor(chatID)
+	msg := tgbotapi.NewMessage(chatID, b.handler.HandleCars(b.cars, carID))
+	msg.ReplyMarkup = GetCarsMenu(b.cars, carID)
+	b.messenger.Send(msg)
+}
+
+// sendControl 发送远程控制菜单
+func (b *Bot) sendControl(chatID int64, carID int) {
+	car, _ := b.findCar(carID)
+	msg := tgbotapi.NewMessage(chatID, b.handler.HandleControl(car))
+	msg.ReplyMarkup = GetControlMenu(carID)
+	b.messenger.Send(msg)
+}
+
+// confirmControl 显示远程操作确认提示
+func (b *Bot) confirmControl(chatID int64, messageID int, key string, carID int) {
+	action, ok := findControlAction(key)
+	if !ok {
+		return
 	}
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ReplyMarkup = GetRefreshMenu("charge")
-	b.api.Send(msg)
+	b.confirms.reset(chatID, messageID)
+	car, _ := b.findCar(carID)
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, b.handler.HandleControlConfirm(car, action))
+	menu := GetControlConfirmMenu(key, carID)
+	edit.ReplyMarkup = &menu
+	b.messenger.Send(edit)
 }
 
-// sendDrive 发送最近一次驾驶信息
-func (b *Bot) sendDrive(chatID int64) {
-	text, err := b.handler.HandleDrive()
-	if err != nil {
-		text = fmt.Sprintf("❌ 获取驾驶信息失败: %v", err)
+// executeControl 执行已确认的远程操作并在原消息中显示结果
+func (b *Bot) executeControl(ctx context.Context, chatID int64, messageID int, key string, carID int) {
+	action, ok := findControlAction(key)
+	if !ok {
+		return
+	}
+	car, _ := b.findCar(carID)
+	log.Printf("执行远程操作: %s, CarID=%d, ChatID=%d", action.Command, carID, chatID)
+
+	// 先移除按钮，避免重复执行
+	b.messenger.Send(tgbotapi.NewEditMessageText(chatID, messageID, "⏳ 正在执行: "+action.Label))
+
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, b.handler.HandleControlCommand(ctx, car, action))
+	menu := GetControlResultMenu(carID)
+	edit.ReplyMarkup = &menu
+	b.messenger.Send(edit)
+}
+
+// carFor 会话当前使用的车辆：/cars 选择 > 配置的会话默认车辆 > 全局默认车辆
+func (b *Bot) carFor(chatID int64) int {
+	if carID, ok := b.prefs.ActiveCar(chatID); ok && b.hasCar(carID) {
+		return carID
+	}
+	if carID, ok := b.defaultCars[chatID]; ok {
+		return carID
+	}
+	return b.defaultCarID
+}
+
+// findCar 按ID查找车辆
+func (b *Bot) findCar(carID int) (models.Car, bool) {
+	for _, car := range b.cars {
+		if car.CarID == carID {
+			return car, true
+		}
+	}
+	return models.Car{CarID: carID}, false
+}
+
+// hasCar 判断车辆是否存在
+func (b *Bot) hasCar(carID int) bool {
+	_, ok := b.findCar(carID)
+	return ok
+}
+
+// sendNotify 发送推送设置菜单
+func (b *Bot) sendNotify(chatID int64) {
+	msg := tgbotapi.NewMessage(chatID, b.handler.HandleNotify())
+	msg.ReplyMarkup = GetNotifyMenu(chatID, b.prefs)
+	b.messenger.Send(msg)
+}
+
+// sendAlerts 发送电量提醒设置菜单
+func (b *Bot) sendAlerts(chatID int64) {
+	settings := b.alerts.Get(chatID)
+	msg := tgbotapi.NewMessage(chatID, b.handler.HandleAlerts(settings))
+	msg.ReplyMarkup = GetAlertsMenu(settings)
+	b.messenger.Send(msg)
+}
+
+// handleAlertsCommand 处理 /alerts [low|target] [电量] 命令
+func (b *Bot) handleAlertsCommand(chatID int64, args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		b.sendAlerts(chatID)
+		return
+	}
+
+	level, err := strconv.Atoi(strings.TrimSuffix(fields[len(fields)-1], "%"))
+	if len(fields) != 2 || err != nil || level < 0 || level > 100 {
+		b.messenger.Send(tgbotapi.NewMessage(chatID, "❓ 用法: /alerts low 15 或 /alerts target 85（0 表示关闭）"))
+		return
+	}
+
+	switch fields[0] {
+	case "low":
+		b.alerts.SetLowBattery(chatID, level)
+	case "target":
+		b.alerts.SetTargetSOC(chatID, level)
+	default:
+		b.messenger.Send(tgbotapi.NewMessage(chatID, "❓ 用法: /alerts low 15 或 /alerts target 85（0 表示关闭）"))
+		return
+	}
+	b.sendAlerts(chatID)
+}
+
+// handleAlertsCallback 处理电量提醒菜单按钮（low_20 / target_80）
+func (b *Bot) handleAlertsCallback(chatID int64, messageID int, data string) {
+	kind, value, ok := strings.Cut(data, "_")
+	level, err := strconv.Atoi(value)
+	if !ok || err != nil {
+		return
+	}
+
+	switch kind {
+	case "low":
+		b.alerts.SetLowBattery(chatID, level)
+	case "target":
+		b.alerts.SetTargetSOC(chatID, level)
+	default:
+		return
+	}
+
+	settings := b.alerts.Get(chatID)
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, b.handler.HandleAlerts(settings))
+	menu := GetAlertsMenu(settings)
+	edit.ReplyMarkup = &menu
+	b.messenger.Send(edit)
+}
+
+// knownGeofences 所有车辆见过的围栏（去重排序）
+func (b *Bot) knownGeofences() []string {
+	var known []string
+	for _, g := range b.geofences {
+		known = append(known, g.Known()...)
+	}
+	slices.Sort(known)
+	return slices.Compact(known)
+}
+
+// sendGeofences 发送围栏通知设置菜单
+func (b *Bot) sendGeofences(chatID int64) {
+	known := b.knownGeofences()
+	msg := tgbotapi.NewMessage(chatID, b.handler.HandleGeofences(known))
+	msg.ReplyMarkup = GetGeofenceMenu(chatID, b.prefs, known)
+	b.messenger.Send(msg)
+}
+
+// handleGeofenceToggle 切换会话对某个围栏的订阅
+func (b *Bot) handleGeofenceToggle(chatID int64, messageID int, id string) {
+	known := b.knownGeofences()
+	for _, name := range known {
+		if geofenceID(name) == id {
+			b.prefs.Toggle(chatID, geofenceTopic(name))
+			break
+		}
 	}
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ReplyMarkup = GetRefreshMenu("drive")
-	b.api.Send(msg)
+	menu := GetGeofenceMenu(chatID, b.prefs, known)
+	b.messenger.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, menu))
 }
@@ -0,0 +1,252 @@
+package bot
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"teslamate-bot/client/teslamatetest"
+	"teslamate-bot/config"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	testChatID   = 100
+	testUserID   = 42
+	viewerChatID = 200
+)
+
+// testBot 使用模拟 TeslaMateApi 与记录发送内容的 Messenger 的 Bot
+type testBot struct {
+	*Bot
+	messenger *recordingMessenger
+	server    *teslamatetest.Server
+	updates   int
+}
+
+// newTestBot 创建测试用 Bot：testChatID 为控制者，viewerChatID 为查看者
+func newTestBot(t *testing.T) *testBot {
+	t.Helper()
+
+	srv := teslamatetest.NewServer()
+	t.Cleanup(srv.Close)
+
+	cfg := &config.Config{
+		Telegram: config.TelegramConfig{
+			WhitelistChatIDs: []int64{testChatID},
+			DefaultRole:      config.RoleController,
+			Chats:            []config.ChatRole{{ChatID: viewerChatID, Role: config.RoleViewer}},
+		},
+		Auth: config.AuthConfig{SessionMinutes: 15, MaxFailures: 5, LockoutMinutes: 15},
+	}
+	m := &recordingMessenger{}
+	b, err := newBot(context.Background(), cfg, m, "tesla_bot", srv.Client(), newTestStore(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testBot{Bot: b, messenger: m, server: srv}
+}
+
+// command 模拟用户在会话中发送命令
+func (tb *testBot) command(chatID int64, text string) []tgbotapi.Chattable {
+	tb.updates++
+	name, _, _ := strings.Cut(text, " ")
+	tb.handleUpdate(context.Background(), tgbotapi.Update{
+		UpdateID: tb.updates,
+		Message: &tgbotapi.Message{
+			MessageID: 1000 + tb.updates,
+			From:      &tgbotapi.User{ID: testUserID},
+			Chat:      &tgbotapi.Chat{ID: chatID, Type: "group"},
+			Text:      text,
+			Entities:  []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(name)}},
+		},
+	})
+	return tb.messenger.Take()
+}
+
+// callback 模拟用户点击消息上的按钮
+func (tb *testBot) callback(chatID int64, messageID int, data string) []tgbotapi.Chattable {
+	tb.updates++
+	tb.handleUpdate(context.Background(), tgbotapi.Update{
+		UpdateID: tb.updates,
+		CallbackQuery: &tgbotapi.CallbackQuery{
+			ID:      "cb-" + data,
+			From:    &tgbotapi.User{ID: testUserID},
+			Message: &tgbotapi.Message{MessageID: messageID, Chat: &tgbotapi.Chat{ID: chatID, Type: "group"}},
+			Data:    data,
+		},
+	})
+	return tb.messenger.Take()
+}
+
+// assertSent 比较发送的内容（包括文字与键盘）
+func assertSent(t *testing.T, got []tgbotapi.Chattable, want ...tgbotapi.Chattable) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("发送了 %d 条内容，期望 %d 条:\n%#v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("第 %d 条内容不一致\n实际: %#v\n期望: %#v", i+1, got[i], want[i])
+		}
+	}
+}
+
+// golden 读取 handlers_test 生成的期望输出
+func golden(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "golden", name+".golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// message 带键盘的新消息
+func message(chatID int64, text string, markup tgbotapi.InlineKeyboardMarkup) tgbotapi.MessageConfig {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = markup
+	return msg
+}
+
+// edit 带键盘的编辑消息
+func edit(chatID int64, messageID int, text string, markup tgbotapi.InlineKeyboardMarkup) tgbotapi.EditMessageTextConfig {
+	e := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	e.ReplyMarkup = &markup
+	return e
+}
+
+func TestStatusRefreshBackToMain(t *testing.T) {
+	tb := newTestBot(t)
+	mainText := golden(t, "start") + "\n\n🚘 当前车辆: 小白（/cars 切换）"
+	mainMenu := GetMainMenu(1, true)
+
+	// /start 显示主菜单
+	assertSent(t, tb.command(testChatID, "/start"),
+		message(testChatID, mainText, mainMenu),
+	)
+
+	// 点击“状态”发送状态页面
+	assertSent(t, tb.callback(testChatID, 1, "status:1"),
+		tgbotapi.NewCallback("cb-status:1", ""),
+		message(testChatID, golden(t, "status"), GetRefreshMenu("status", 1)),
+	)
+
+	// 刷新时在原消息中更新，并忽略缓存重新请求
+	assertSent(t, tb.callback(testChatID, 2, "refresh_status:1"),
+		tgbotapi.NewCallback("cb-refresh_status:1", ""),
+		edit(testChatID, 2, golden(t, "status"), GetRefreshMenu("status", 1)),
+	)
+	if n := countRequests(tb.server, "GET /api/v1/cars/1/status"); n != 2 {
+		t.Errorf("状态请求 %d 次，期望 2 次", n)
+	}
+
+	// 返回主菜单
+	assertSent(t, tb.callback(testChatID, 2, "back_main"),
+		tgbotapi.NewCallback("cb-back_main", ""),
+		edit(testChatID, 2, mainText, mainMenu),
+	)
+}
+
+func TestSwitchCarThenStatus(t *testing.T) {
+	tb := newTestBot(t)
+
+	assertSent(t, tb.command(testChatID, "/cars"),
+		message(testChatID, tb.handler.HandleCars(tb.cars, 1), GetCarsMenu(tb.cars, 1)),
+	)
+	assertSent(t, tb.callback(testChatID, 1, "select_car:2"),
+		tgbotapi.NewCallback("cb-select_car:2", ""),
+		edit(testChatID, 1, tb.handler.HandleCars(tb.cars, 2), GetCarsMenu(tb.cars, 2)),
+	)
+
+	// 之后的命令使用切换后的车辆
+	assertSent(t, tb.command(testChatID, "/status"),
+		message(testChatID, golden(t, "status_nulls"), GetRefreshMenu("status", 2)),
+	)
+}
+
+func TestControlConfirmAndExecute(t *testing.T) {
+	tb := newTestBot(t)
+	lock, _ := findControlAction("lock")
+
+	assertSent(t, tb.command(testChatID, "/control"),
+		message(testChatID, golden(t, "control"), GetControlMenu(1)),
+	)
+	assertSent(t, tb.callback(testChatID, 1, "ctl_lock:1"),
+		tgbotapi.NewCallback("cb-ctl_lock:1", ""),
+		edit(testChatID, 1, tb.handler.HandleControlConfirm(tb.cars[0], lock), GetControlConfirmMenu("lock", 1)),
+	)
+	if n := countRequests(tb.server, "POST /api/v1/cars/1/command/door_lock"); n != 0 {
+		t.Fatalf("确认前已发送指令 %d 次", n)
+	}
+
+	assertSent(t, tb.callback(testChatID, 1, "ctlok_lock:1"),
+		tgbotapi.NewCallback("cb-ctlok_lock:1", ""),
+		tgbotapi.NewEditMessageText(testChatID, 1, "⏳ 正在执行: "+lock.Label),
+		edit(testChatID, 1, golden(t, "control_command_lock"), GetControlResultMenu(1)),
+	)
+	if n := countRequests(tb.server, "POST /api/v1/cars/1/command/door_lock"); n != 1 {
+		t.Errorf("指令发送 %d 次，期望 1 次", n)
+	}
+}
+
+func TestControlConfirmTwice(t *testing.T) {
+	tb := newTestBot(t)
+	tb.callback(testChatID, 1, "ctl_lock:1")
+	tb.callback(testChatID, 1, "ctlok_lock:1")
+
+	// 重复点击确认按钮不会再次发送指令
+	assertSent(t, tb.callback(testChatID, 1, "ctlok_lock:1"),
+		tgbotapi.NewCallback("cb-ctlok_lock:1", "⏳ 已在执行"),
+	)
+	if n := countRequests(tb.server, "POST /api/v1/cars/1/command/door_lock"); n != 1 {
+		t.Fatalf("指令发送 %d 次，期望 1 次", n)
+	}
+
+	// 其他消息上的确认不受影响
+	tb.callback(testChatID, 2, "ctl_lock:1")
+	tb.callback(testChatID, 2, "ctlok_lock:1")
+	// 在同一条消息上重新确认后可以再次执行
+	tb.callback(testChatID, 1, "ctl_lock:1")
+	tb.callback(testChatID, 1, "ctlok_lock:1")
+	if n := countRequests(tb.server, "POST /api/v1/cars/1/command/door_lock"); n != 3 {
+		t.Errorf("指令发送 %d 次，期望 3 次", n)
+	}
+}
+
+func TestViewerCannotControl(t *testing.T) {
+	tb := newTestBot(t)
+
+	assertSent(t, tb.command(viewerChatID, "/control"),
+		tgbotapi.NewMessage(viewerChatID, golden(t, "denied")),
+	)
+	assertSent(t, tb.callback(viewerChatID, 1, "ctlok_lock:1"),
+		tgbotapi.NewCallbackWithAlert("cb-ctlok_lock:1", golden(t, "denied")),
+	)
+	if reqs := tb.server.Requests(); len(reqs) != 1 {
+		// 只有创建 Bot 时获取车辆列表的请求
+		t.Errorf("查看者触发了请求: %v", reqs)
+	}
+}
+
+func TestUnknownChatIgnored(t *testing.T) {
+	tb := newTestBot(t)
+
+	assertSent(t, tb.command(999, "/status"))
+	assertSent(t, tb.callback(999, 1, "status:1"))
+}
+
+// countRequests 统计模拟服务器收到的指定请求次数
+func countRequests(srv *teslamatetest.Server, request string) int {
+	n := 0
+	for _, r := range srv.Requests() {
+		if r == request {
+			n++
+		}
+	}
+	return n
+}
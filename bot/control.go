@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"sync"
+	"time"
+
+	"teslamate-bot/client"
+)
+
+// controlConfirmTTL 已使用的确认按钮的记录保留时长
+const controlConfirmTTL = time.Hour
+
+// controlAction /control 菜单中的一个远程操作
+type controlAction struct {
+	Key     string // 回调数据中的标识
+	Label   string
+	Command client.Command
+	Payload any
+}
+
+// controlActions /control 菜单中的远程操作（按两列排列，成对的开/关放在同一行）
+var controlActions = []controlAction{
+	{Key: "lock", Label: "🔒 锁车", Command: client.CommandDoorLock},
+	{Key: "unlock", Label: "🔓 解锁", Command: client.CommandDoorUnlock},
+	{Key: "climate_on", Label: "❄️ 开启空调", Command: client.CommandClimateStart},
+	{Key: "climate_off", Label: "🌬️ 关闭空调", Command: client.CommandClimateStop},
+	{Key: "charge_start", Label: "🔌 开始充电", Command: client.CommandChargeStart},
+	{Key: "charge_stop", Label: "⏹️ 停止充电", Command: client.CommandChargeStop},
+	{Key: "port_open", Label: "🔋 打开充电口", Command: client.CommandChargePortDoorOpen},
+	{Key: "port_close", Label: "🔋 关闭充电口", Command: client.CommandChargePortDoorClose},
+	{Key: "sentry_on", Label: "🛡️ 开启哨兵", Command: client.CommandSetSentryMode, Payload: client.SentryModePayload{On: true}},
+	{Key: "sentry_off", Label: "🛡️ 关闭哨兵", Command: client.CommandSetSentryMode, Payload: client.SentryModePayload{On: false}},
+	{Key: "limit_80", Label: "🎯 充电上限 80%", Command: client.CommandSetChargeLimit, Payload: client.ChargeLimitPayload{Percent: 80}},
+	{Key: "limit_90", Label: "🎯 充电上限 90%", Command: client.CommandSetChargeLimit, Payload: client.ChargeLimitPayload{Percent: 90}},
+	{Key: "honk", Label: "📯 鸣笛", Command: client.CommandHonkHorn},
+	{Key: "flash", Label: "💡 闪灯", Command: client.CommandFlashLights},
+	{Key: "wake", Label: "⏰ 唤醒车辆", Command: client.CommandWakeUp},
+}
+
+// findControlAction 按标识查找远程操作
+func findControlAction(key string) (controlAction, bool) {
+	for _, a := range controlActions {
+		if a.Key == key {
+			return a, true
+		}
+	}
+	return controlAction{}, false
+}
+
+// controlConfirm 远程操作确认消息的标识
+type controlConfirm struct {
+	chatID    int64
+	messageID int
+}
+
+// controlConfirms 已使用的远程操作确认，重复点击确认按钮时不重复执行
+type controlConfirms struct {
+	mu   sync.Mutex
+	used map[controlConfirm]time.Time
+}
+
+// claim 使用确认消息，该确认正在执行或已执行时返回 false
+func (c *controlConfirms) claim(chatID int64, messageID int, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.used == nil {
+		c.used = make(map[controlConfirm]time.Time)
+	}
+	for key, at := range c.used {
+		if now.Sub(at) >= controlConfirmTTL {
+			delete(c.used, key)
+		}
+	}
+
+	key := controlConfirm{chatID: chatID, messageID: messageID}
+	if _, ok := c.used[key]; ok {
+		return false
+	}
+	c.used[key] = now
+	return true
+}
+
+// reset 在同一条消息上重新显示确认提示后允许再次执行
+func (c *controlConfirms) reset(chatID int64, messageID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.used, controlConfirm{chatID: chatID, messageID: messageID})
+}
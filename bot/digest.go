@@ -0,0 +1,100 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"teslamate-bot/client"
+	"teslamate-bot/config"
+	"teslamate-bot/models"
+)
+
+// digestJob 定时摘要任务
+type digestJob struct {
+	cfg    config.DigestConfig
+	client client.TeslaMateAPI
+	cars   []models.Car
+	notify func(n Notification)
+	ctx    context.Context // 由 Scheduler.Start 设置
+}
+
+// Run 为每辆车生成摘要并发送（实现 cron.Job）
+func (j *digestJob) Run() {
+	// 定时任务按分钟触发，统计时间取整到分钟，同时触发的摘要可以共用查询结果
+	now := time.Now().Truncate(time.Minute)
+	for _, car := range j.cars {
+		text, err := j.build(j.ctx, car, now)
+		if err != nil {
+			log.Printf("生成摘要 %s 失败 (CarID: %d): %v", j.cfg.Name, car.CarID, err)
+			continue
+		}
+
+		if len(j.cfg.ChatIDs) == 0 {
+			j.notify(Notification{Topic: "digest", Text: text})
+			continue
+		}
+		for _, chatID := range j.cfg.ChatIDs {
+			j.notify(Notification{ChatID: chatID, Text: text})
+		}
+	}
+}
+
+// build 统计某辆车截至 now 的一个周期内的驾驶与充电数据
+func (j *digestJob) build(ctx context.Context, car models.Car, now time.Time) (string, error) {
+	start := now.Add(-j.cfg.PeriodDuration())
+
+	drives, units, err := j.client.GetDrives(ctx, car.CarID, start, now)
+	if err != nil {
+		return "", err
+	}
+	charges, err := j.client.GetCharges(ctx, car.CarID, start, now)
+	if err != nil {
+		return "", err
+	}
+
+	var distance, energyUsed float64
+	for _, d := range drives {
+		distance += d.OdometerDetails.OdometerDistance
+		energyUsed += d.EnergyConsumedNet
+	}
+
+	var energyCharged, cost float64
+	for _, c := range charges {
+		energyCharged += c.ChargeEnergyAdded
+		cost += c.Cost
+	}
+
+	consumption := "—"
+	if distance > 0 {
+		consumption = fmt.Sprintf("%.0f Wh/%s", energyUsed*1000/distance, units.UnitOfLength)
+	}
+
+	lines := []string{
+		"📊 " + j.cfg.Name,
+		"━━━━━━━━━━━━━━━━━━━━",
+		"🚗 " + carName(car),
+		fmt.Sprintf("📅 %s → %s", start.Format("2006-01-02 15:04"), now.Format("2006-01-02 15:04")),
+		fmt.Sprintf("🚗 驾驶: %d 次，共 %.1f %s", len(drives), distance, units.UnitOfLength),
+		fmt.Sprintf("⚡ 耗电: %.2f kWh（平均 %s）", energyUsed, consumption),
+		fmt.Sprintf("🔌 充电: %d 次，充入 %.2f kWh，费用 ¥%.2f", len(charges), energyCharged, cost),
+	}
+
+	// 当前电量与电池健康度获取失败时不影响摘要发送
+	if statusResp, err := j.client.GetCarStatus(ctx, car.CarID); err == nil {
+		battery := statusResp.Data.Status.BatteryDetails
+		lines = append(lines, fmt.Sprintf("🔋 当前电量: %d%% (%.0f %s)",
+			battery.BatteryLevel, battery.RatedBatteryRange, statusResp.Data.Units.UnitOfLength))
+	} else {
+		log.Printf("摘要获取车辆状态失败: %v", err)
+	}
+	if healthResp, err := j.client.GetBatteryHealth(ctx, car.CarID); err == nil {
+		lines = append(lines, fmt.Sprintf("💚 电池健康度: %.2f%%", healthResp.Data.BatteryHealth.BatteryHealthPercentage))
+	} else {
+		log.Printf("摘要获取电池健康度失败: %v", err)
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,57 @@
+package bot
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"teslamate-bot/client"
+	"teslamate-bot/client/teslamatetest"
+	"teslamate-bot/config"
+	"teslamate-bot/models"
+)
+
+func TestDigestBuild(t *testing.T) {
+	srv := teslamatetest.NewServer()
+	defer srv.Close()
+
+	job := &digestJob{
+		cfg:    config.DigestConfig{Name: "每周摘要", Period: "week"},
+		client: srv.Client(),
+	}
+	now := time.Date(2024, 5, 6, 8, 0, 0, 0, time.UTC)
+
+	text, err := job.build(t.Context(), models.Car{CarID: 1, Name: "小白"}, now)
+	assertGolden(t, "digest_week", renderResult(text, err))
+
+	// 电池健康度获取失败时仍发送摘要
+	srv.Handle("/api/v1/cars/1/battery-health", http.StatusBadGateway, "")
+	text, err = job.build(client.WithoutCache(t.Context()), models.Car{CarID: 1, Name: "小白"}, now)
+	assertGolden(t, "digest_week_no_health", renderResult(text, err))
+
+	// 驾驶记录获取失败时不发送
+	srv.Handle("/api/v1/cars/1/drives", http.StatusBadGateway, "")
+	if _, err := job.build(client.WithoutCache(t.Context()), models.Car{CarID: 1}, now); err == nil {
+		t.Error("驾驶记录获取失败时生成了摘要")
+	}
+}
+
+func TestDigestRun(t *testing.T) {
+	srv := teslamatetest.NewServer()
+	defer srv.Close()
+
+	var got []Notification
+	job := &digestJob{
+		cfg:    config.DigestConfig{Name: "每日摘要", ChatIDs: []int64{100, 200}},
+		client: srv.Client(),
+		cars:   []models.Car{{CarID: 1, Name: "小白"}},
+		notify: func(n Notification) { got = append(got, n) },
+		ctx:    t.Context(),
+	}
+	job.Run()
+
+	// 指定了接收会话时只发给这些会话
+	if len(got) != 2 || got[0].ChatID != 100 || got[1].ChatID != 200 || got[0].Topic != "" {
+		t.Errorf("通知 = %+v, 期望发送给会话 100 和 200", got)
+	}
+}
@@ -0,0 +1,325 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"teslamate-bot/client"
+	"teslamate-bot/config"
+	"teslamate-bot/models"
+	"teslamate-bot/store"
+)
+
+const (
+	// drainKey 停车掉电记录在状态存储中的键前缀
+	drainKey = "drain"
+	// drainBaselineMaxAge 使用驾驶/充电记录作为停车起点的最长时间
+	drainBaselineMaxAge = 7 * 24 * time.Hour
+	// kmPerMile 英里换算公里
+	kmPerMile = 1.609344
+)
+
+// drainPeriod 一段停车时间内的电量变化与状态分布
+type drainPeriod struct {
+	CarName    string  `json:"car_name"`
+	StartedAt  string  `json:"started_at"`
+	StartLevel int     `json:"start_level"`
+	StartRange float64 `json:"start_range"`
+	LastAt     string  `json:"last_at"`
+	LastLevel  int     `json:"last_level"`
+	LastRange  float64 `json:"last_range"`
+	Unit       string  `json:"unit"`
+	AsleepSec  int64   `json:"asleep_sec"`
+	OnlineSec  int64   `json:"online_sec"`
+	OfflineSec int64   `json:"offline_sec"`
+	SentrySec  int64   `json:"sentry_sec"`
+}
+
+// drainState 停车掉电的持久化状态
+type drainState struct {
+	Current    *drainPeriod `json:"current,omitempty"`
+	Last       *drainPeriod `json:"last,omitempty"`
+	ReportDate string       `json:"report_date,omitempty"` // 最近一次早间推送的日期
+}
+
+// DrainTracker 记录停车期间（上次驾驶结束到下次驾驶或充电）的掉电情况
+//
+// mu 只保护 state 与 efficiency，请求 TeslaMate API 时不持有锁，
+// 避免 /drain 等待进行中的轮询。
+type DrainTracker struct {
+	mu         sync.Mutex
+	key        string
+	carID      int
+	client     client.TeslaMateAPI
+	store      *store.Store
+	reportTime string
+	loc        *time.Location // 早间报告与显示时间使用的时区
+	state      drainState
+	efficiency float64 // 车辆能耗（kWh/km），首次使用时从车辆详情获取
+}
+
+// NewDrainTracker 创建停车掉电记录器并恢复上次保存的状态
+func NewDrainTracker(carID int, cfg config.DrainWatchConfig, loc *time.Location, tmClient client.TeslaMateAPI, st *store.Store) *DrainTracker {
+	d := &DrainTracker{
+		key:        carKey(drainKey, carID),
+		carID:      carID,
+		client:     tmClient,
+		store:      st,
+		reportTime: cfg.ReportTime,
+		loc:        loc,
+	}
+	if _, err := st.Get(d.key, &d.state); err != nil {
+		log.Printf("恢复停车掉电记录失败: %v", err)
+	}
+	return d
+}
+
+// Name 监控项名称
+func (d *DrainTracker) Name() string {
+	return d.key
+}
+
+// Check 累计停车期间的状态快照，驾驶或充电开始时结束本次记录
+func (d *DrainTracker) Check(ctx context.Context, statusResp *models.StatusResponse) []Notification {
+	status := &statusResp.Data.Status
+	now := time.Now()
+
+	charging := status.State == "charging" || status.ChargingDetails.ChargingState == "Charging"
+	parked := isParked(status) && !charging
+
+	// 只有轮询会修改 Current，因此可以先在锁外获取新停车时段的起点
+	var start *drainPeriod
+	if parked && d.current() == nil {
+		start = d.newPeriod(ctx, statusResp, now)
+	}
+
+	report := d.update(status, parked, start, now)
+	if report == nil {
+		return nil
+	}
+	return []Notification{{
+		Topic: "drain",
+		Text:  d.format("🌅 早间掉电报告", report, d.carEfficiency(ctx)),
+	}}
+}
+
+// update 更新停车时段并保存，需要发送早间报告时返回当前时段的副本
+func (d *DrainTracker) update(status *models.CarStatus, parked bool, start *drainPeriod, now time.Time) *drainPeriod {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !parked {
+		if d.state.Current != nil {
+			d.state.Last = d.state.Current
+			d.state.Current = nil
+			d.save()
+		}
+		return nil
+	}
+
+	if d.state.Current == nil {
+		d.state.Current = start
+	} else {
+		d.accumulate(status, now)
+	}
+	if level := status.BatteryDetails.BatteryLevel; level > 0 {
+		d.state.Current.LastLevel = level
+		d.state.Current.LastRange = status.BatteryDetails.RatedBatteryRange
+	}
+	d.state.Current.LastAt = now.Format(time.RFC3339)
+
+	var report *drainPeriod
+	if d.reportDue(now) {
+		d.state.ReportDate = now.In(d.loc).Format(time.DateOnly)
+		p := *d.state.Current
+		report = &p
+	}
+
+	d.save()
+	return report
+}
+
+// current 当前停车时段
+func (d *DrainTracker) current() *drainPeriod {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.state.Current
+}
+
+// Report 生成 /drain 报告：优先显示正在进行的停车时段，否则显示上一次
+func (d *DrainTracker) Report(ctx context.Context) string {
+	d.mu.Lock()
+	var title string
+	var p drainPeriod
+	switch {
+	case d.state.Current != nil:
+		title, p = "🧛 停车掉电（进行中）", *d.state.Current
+	case d.state.Last != nil:
+		title, p = "🧛 上次停车掉电", *d.state.Last
+	}
+	d.mu.Unlock()
+
+	if title == "" {
+		return "🧛 暂无停车掉电记录\n\n后台监控会在车辆停车后开始记录"
+	}
+	return d.format(title, &p, d.carEfficiency(ctx))
+}
+
+// newPeriod 开始新的停车时段，优先使用最近一次驾驶/充电结束时的电量作为起点
+func (d *DrainTracker) newPeriod(ctx context.Context, statusResp *models.StatusResponse, now time.Time) *drainPeriod {
+	status := &statusResp.Data.Status
+	p := &drainPeriod{
+		CarName:    status.DisplayName,
+		StartedAt:  now.Format(time.RFC3339),
+		StartLevel: status.BatteryDetails.BatteryLevel,
+		StartRange: status.BatteryDetails.RatedBatteryRange,
+		LastAt:     now.Format(time.RFC3339),
+		Unit:       statusResp.Data.Units.UnitOfLength,
+	}
+
+	var baseline time.Time
+	if drive, _, err := d.client.GetLatestDrive(ctx, d.carID); err == nil {
+		if end, err := time.Parse(time.RFC3339, drive.EndDate); err == nil {
+			baseline = end
+			p.StartLevel = drive.BatteryDetails.EndBatteryLevel
+			p.StartRange = drive.RangeRated.EndRange
+		}
+	}
+	if charge, err := d.client.GetLatestCharge(ctx, d.carID); err == nil {
+		if end, err := time.Parse(time.RFC3339, charge.EndDate); err == nil && end.After(baseline) {
+			baseline = end
+			p.StartLevel = charge.BatteryDetails.EndBatteryLevel
+			p.StartRange = charge.RangeRated.EndRange
+		}
+	}
+
+	if !baseline.IsZero() && baseline.Before(now) && now.Sub(baseline) <= drainBaselineMaxAge {
+		p.StartedAt = baseline.Format(time.RFC3339)
+	} else {
+		p.StartLevel = status.BatteryDetails.BatteryLevel
+		p.StartRange = status.BatteryDetails.RatedBatteryRange
+	}
+	return p
+}
+
+// accumulate 将距上次快照的时间计入当前车辆状态
+func (d *DrainTracker) accumulate(status *models.CarStatus, now time.Time) {
+	p := d.state.Current
+	last, err := time.Parse(time.RFC3339, p.LastAt)
+	if err != nil || !now.After(last) {
+		return
+	}
+	elapsed := int64(now.Sub(last).Seconds())
+
+	switch status.State {
+	case "asleep", "suspended":
+		p.AsleepSec += elapsed
+	case "offline":
+		p.OfflineSec += elapsed
+	default:
+		p.OnlineSec += elapsed
+	}
+	if status.CarStatusInfo.SentryMode {
+		p.SentrySec += elapsed
+	}
+}
+
+// reportDue 判断是否需要发送今天的早间报告（按配置的时区计算日期与时间）
+func (d *DrainTracker) reportDue(now time.Time) bool {
+	now = now.In(d.loc)
+	if d.reportTime == "" || d.state.ReportDate == now.Format(time.DateOnly) {
+		return false
+	}
+	at, err := time.Parse("15:04", d.reportTime)
+	if err != nil {
+		return false
+	}
+	// 错过推送时间一小时以上（如Bot在此期间未运行）则当天不再补发
+	due := time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, d.loc)
+	return !now.Before(due) && now.Sub(due) < time.Hour
+}
+
+// save 保存状态（调用方需持有锁）
+func (d *DrainTracker) save() {
+	if err := d.store.Put(d.key, d.state); err != nil {
+		log.Printf("保存停车掉电记录失败: %v", err)
+	}
+}
+
+// carEfficiency 车辆能耗（kWh/km），首次使用时从车辆详情获取，获取失败时为 0
+func (d *DrainTracker) carEfficiency(ctx context.Context) float64 {
+	d.mu.Lock()
+	efficiency := d.efficiency
+	d.mu.Unlock()
+	if efficiency > 0 {
+		return efficiency
+	}
+
+	car, err := d.client.GetCarDetails(ctx, d.carID)
+	if err != nil {
+		log.Printf("获取车辆能耗失败: %v", err)
+		return 0
+	}
+
+	d.mu.Lock()
+	d.efficiency = car.CarDetails.Efficiency
+	d.mu.Unlock()
+	return car.CarDetails.Efficiency
+}
+
+// estimateKWh 根据续航损失与车辆能耗估算耗电量
+func estimateKWh(p *drainPeriod, efficiency float64) (float64, bool) {
+	lost := p.StartRange - p.LastRange
+	if p.Unit == "mi" {
+		lost *= kmPerMile
+	}
+	return lost * efficiency, efficiency > 0
+}
+
+// format 格式化停车掉电报告
+func (d *DrainTracker) format(title string, p *drainPeriod, efficiency float64) string {
+	startedAt, _ := time.Parse(time.RFC3339, p.StartedAt)
+	lastAt, _ := time.Parse(time.RFC3339, p.LastAt)
+	parked := lastAt.Sub(startedAt)
+
+	energy := "未知"
+	if kwh, ok := estimateKWh(p, efficiency); ok {
+		energy = fmt.Sprintf("%.2f kWh", kwh)
+		if hours := parked.Hours(); hours > 0 {
+			energy += fmt.Sprintf(" (%.2f kWh/天)", kwh/hours*24)
+		}
+	}
+
+	tracked := p.AsleepSec + p.OnlineSec + p.OfflineSec
+	lines := []string{
+		title,
+		"━━━━━━━━━━━━━━━━━━━━",
+		"🚗 " + p.CarName,
+		"🕐 停车开始: " + startedAt.In(d.loc).Format("2006-01-02 15:04"),
+		"⏱️ 停车时长: " + formatDuration(parked),
+		fmt.Sprintf("🔋 电量: %d%% → %d%% (-%d%%)", p.StartLevel, p.LastLevel, p.StartLevel-p.LastLevel),
+		fmt.Sprintf("📏 续航: %.0f → %.0f %s (-%.0f %s)", p.StartRange, p.LastRange, p.Unit, p.StartRange-p.LastRange, p.Unit),
+		"⚡ 估算耗电: " + energy,
+		"━━━━━━━━━━━━━━━━━━━━",
+		"😴 休眠: " + formatShare(p.AsleepSec, tracked),
+		"🟢 在线: " + formatShare(p.OnlineSec, tracked),
+	}
+	if p.OfflineSec > 0 {
+		lines = append(lines, "⚫ 离线: "+formatShare(p.OfflineSec, tracked))
+	}
+	lines = append(lines, "🚨 哨兵模式: "+formatShare(p.SentrySec, tracked))
+
+	return strings.Join(lines, "\n")
+}
+
+// formatShare 格式化时长及其占比
+func formatShare(sec, total int64) string {
+	if total <= 0 {
+		return formatDuration(time.Duration(sec) * time.Second)
+	}
+	return fmt.Sprintf("%s (%.0f%%)", formatDuration(time.Duration(sec)*time.Second), float64(sec)/float64(total)*100)
+}
@@ -0,0 +1,130 @@
+package bot
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"teslamate-bot/client/teslamatetest"
+	"teslamate-bot/config"
+	"teslamate-bot/models"
+)
+
+// carState 车辆状态（如 asleep、online、offline），拔枪停车并关闭哨兵模式
+func carState(state string) func(s *models.CarStatus) {
+	return func(s *models.CarStatus) {
+		unplugged(s)
+		s.State = state
+		s.DrivingDetails.ShiftState = "P"
+		s.CarStatusInfo.SentryMode = false
+	}
+}
+
+// sentry 哨兵模式开启
+func sentry(s *models.CarStatus) {
+	s.CarStatusInfo.SentryMode = true
+}
+
+func TestDrainTrackerPeriod(t *testing.T) {
+	srv := teslamatetest.NewServer()
+	defer srv.Close()
+
+	d := NewDrainTracker(1, config.DrainWatchConfig{}, time.UTC, srv.Client(), newTestStore(t))
+
+	// 充电时不记录
+	runSteps(t, d, []checkStep{{status: testStatus(t)}})
+	if d.state.Current != nil {
+		t.Fatalf("充电时开始了停车时段: %+v", d.state.Current)
+	}
+
+	// fixture 中的行程与充电记录已超过 7 天，以当前状态为起点
+	runSteps(t, d, []checkStep{{status: testStatus(t, carState("online"))}})
+	if p := d.state.Current; p == nil || p.StartLevel != 72 || p.AsleepSec != 0 {
+		t.Fatalf("停车时段 = %+v, 期望从当前电量 72%% 开始", p)
+	}
+
+	// 距上次快照的时间计入当前状态
+	d.state.Current.LastAt = ago(time.Hour)
+	runSteps(t, d, []checkStep{{status: testStatus(t, carState("asleep"))}})
+	d.state.Current.LastAt = ago(30 * time.Minute)
+	runSteps(t, d, []checkStep{{status: testStatus(t, carState("online"), sentry)}})
+
+	p := d.state.Current
+	if p.AsleepSec < 3590 || p.AsleepSec > 3610 {
+		t.Errorf("休眠时长 = %d 秒, 期望约 3600", p.AsleepSec)
+	}
+	if p.OnlineSec < 1790 || p.OnlineSec > 1810 || p.SentrySec != p.OnlineSec {
+		t.Errorf("在线 %d 秒, 哨兵 %d 秒, 期望均约 1800", p.OnlineSec, p.SentrySec)
+	}
+
+	// 驾驶结束本次记录
+	runSteps(t, d, []checkStep{{status: testStatus(t, carState("online"), shift("D"))}})
+	if d.state.Current != nil || d.state.Last == nil || d.state.Last.AsleepSec != p.AsleepSec {
+		t.Errorf("驾驶后状态 = %+v, 期望结束当前时段", d.state)
+	}
+}
+
+func TestDrainTrackerBaseline(t *testing.T) {
+	srv := teslamatetest.NewServer()
+	defer srv.Close()
+
+	// 最近一次行程在两小时前结束，停车起点为行程结束时的电量
+	end := time.Now().Add(-2 * time.Hour).UTC().Truncate(time.Second)
+	body := strings.Replace(string(teslamatetest.Fixture("drives.json")), "2024-05-01T08:29:47Z", end.Format(time.RFC3339), 1)
+	srv.Handle("/api/v1/cars/1/drives", http.StatusOK, body)
+
+	d := NewDrainTracker(1, config.DrainWatchConfig{}, time.UTC, srv.Client(), newTestStore(t))
+	runSteps(t, d, []checkStep{{status: testStatus(t, carState("asleep"))}})
+
+	p := d.state.Current
+	if p == nil || p.StartedAt != end.Format(time.RFC3339) || p.StartLevel != 84 || p.StartRange != 410.2 || p.LastLevel != 72 {
+		t.Errorf("停车时段 = %+v, 期望从 %s 的 84%% 开始", p, end.Format(time.RFC3339))
+	}
+}
+
+func TestDrainTrackerReportDue(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*60*60)
+	tests := []struct {
+		name       string
+		now        time.Time
+		reportDate string
+		want       bool
+	}{
+		{"当地时间未到", time.Date(2026, 10, 17, 23, 59, 0, 0, time.UTC), "", false},
+		{"当地 08:00", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), "", true},
+		{"当地 08:59", time.Date(2026, 10, 18, 0, 59, 0, 0, time.UTC), "", true},
+		{"错过一小时以上不补发", time.Date(2026, 10, 18, 1, 0, 0, 0, time.UTC), "", false},
+		{"当天已发送", time.Date(2026, 10, 18, 0, 30, 0, 0, time.UTC), "2026-10-18", false},
+		// UTC 日期仍是前一天，按当地日期判断
+		{"前一天已发送", time.Date(2026, 10, 18, 0, 30, 0, 0, time.UTC), "2026-10-17", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &DrainTracker{reportTime: "08:00", loc: loc, state: drainState{ReportDate: tt.reportDate}}
+			if got := d.reportDue(tt.now); got != tt.want {
+				t.Errorf("reportDue(%s) = %v, 期望 %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDrainTrackerMorningReport(t *testing.T) {
+	srv := teslamatetest.NewServer()
+	defer srv.Close()
+
+	// 报告时间取当前时间所在的分钟，保证本次检查时已到推送时间
+	loc := time.FixedZone("UTC-5", -5*60*60)
+	cfg := config.DrainWatchConfig{ReportTime: time.Now().In(loc).Format("15:04")}
+	d := NewDrainTracker(1, cfg, loc, srv.Client(), newTestStore(t))
+
+	asleep := testStatus(t, carState("asleep"))
+	runSteps(t, d, []checkStep{
+		{status: asleep, want: []string{"drain: 🌅 早间掉电报告"}},
+		// 每天只推送一次
+		{status: asleep},
+	})
+	if want := time.Now().In(loc).Format(time.DateOnly); d.state.ReportDate != want {
+		t.Errorf("推送日期 = %q, 期望当地日期 %q", d.state.ReportDate, want)
+	}
+}
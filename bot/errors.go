@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+
+	"teslamate-bot/client"
+)
+
+// errorText 错误的用户提示
+//
+// TeslaMate API 错误按类型给出提示，状态码、响应内容等详细信息只写入日志。
+func errorText(err error) string {
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		return err.Error()
+	}
+
+	switch apiErr.Kind {
+	case client.KindUnauthorized:
+		return "TeslaMate API 认证失败，请检查 api_key 配置"
+	case client.KindNotFound:
+		if apiErr.StatusCode == 0 && apiErr.Message != "" {
+			return apiErr.Message
+		}
+		return "未找到车辆或数据"
+	case client.KindCarUnavailable:
+		return "车辆处于休眠或离线状态，请先唤醒车辆后重试"
+	case client.KindTimeout:
+		return "请求 TeslaMate API 超时，请稍后重试"
+	case client.KindDecode:
+		return "无法解析 TeslaMate API 返回的数据，请确认 TeslaMateApi 版本"
+	case client.KindUpstream:
+		return fmt.Sprintf("TeslaMate API 服务异常（%d），请稍后重试", apiErr.StatusCode)
+	case client.KindUnavailable:
+		return apiErr.Message
+	case client.KindNetwork:
+		return "无法连接 TeslaMate API，请检查网络或 api_url 配置"
+	case client.KindCanceled:
+		return "请求已取消"
+	case client.KindBadRequest:
+		return fmt.Sprintf("TeslaMate API 拒绝了请求（%d）", apiErr.StatusCode)
+	case client.KindRejected:
+		return fmt.Sprintf("车辆未执行该操作（%s）", apiErr.Body)
+	}
+	return "请求 TeslaMate API 失败，请稍后重试"
+}
@@ -0,0 +1,203 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"slices"
+	"sync"
+
+	"teslamate-bot/config"
+	"teslamate-bot/models"
+	"teslamate-bot/store"
+)
+
+const (
+	// geofenceKey 地理围栏状态在状态存储中的键前缀
+	geofenceKey = "geofence"
+	// geofenceExitFactor 离开本地围栏的半径倍数，避免定位抖动导致反复进出
+	geofenceExitFactor = 1.2
+	// earthRadiusMeters 地球平均半径（米）
+	earthRadiusMeters = 6371000
+)
+
+// geofenceState 地理围栏的持久化状态
+type geofenceState struct {
+	Initialized bool     `json:"initialized"`
+	Inside      []string `json:"inside"` // 当前所在的围栏
+	Seen        []string `json:"seen"`   // 见过的 TeslaMate 围栏
+}
+
+// GeofenceTracker 跟踪车辆进出 TeslaMate 围栏及本地配置的围栏
+type GeofenceTracker struct {
+	mu    sync.Mutex
+	key   string
+	store *store.Store
+	local []config.GeofenceConfig
+	state geofenceState
+}
+
+// NewGeofenceTracker 创建地理围栏跟踪器并恢复上次保存的状态
+func NewGeofenceTracker(carID int, local []config.GeofenceConfig, st *store.Store) *GeofenceTracker {
+	g := &GeofenceTracker{key: carKey(geofenceKey, carID), store: st, local: local}
+	if _, err := st.Get(g.key, &g.state); err != nil {
+		log.Printf("恢复地理围栏状态失败: %v", err)
+	}
+	return g
+}
+
+// Name 监控项名称
+func (g *GeofenceTracker) Name() string {
+	return g.key
+}
+
+// Check 比较当前所在围栏与上次的差异，生成到达/离开通知
+func (g *GeofenceTracker) Check(ctx context.Context, statusResp *models.StatusResponse) []Notification {
+	status := &statusResp.Data.Status
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	inside := g.currentPlaces(status)
+	prev := g.state.Inside
+
+	changed := !slices.Equal(inside, prev)
+	if name := status.CarGeodata.Geofence; name != "" && !slices.Contains(g.state.Seen, name) {
+		g.state.Seen = append(g.state.Seen, name)
+		slices.Sort(g.state.Seen)
+		changed = true
+	}
+
+	var messages []Notification
+	if g.state.Initialized {
+		for _, name := range prev {
+			if !slices.Contains(inside, name) {
+				messages = append(messages, Notification{
+					Topic: geofenceTopic(name),
+					Text:  formatGeofenceEvent("🚗 已离开", name, status),
+				})
+			}
+		}
+		for _, name := range inside {
+			if !slices.Contains(prev, name) {
+				messages = append(messages, Notification{
+					Topic: geofenceTopic(name),
+					Text:  formatGeofenceEvent("📍 已到达", name, status),
+				})
+			}
+		}
+	} else {
+		// 首次运行只记录基线，不推送
+		g.state.Initialized = true
+		changed = true
+	}
+
+	if changed {
+		g.state.Inside = inside
+		if err := g.store.Put(g.key, g.state); err != nil {
+			log.Printf("保存地理围栏状态失败: %v", err)
+		}
+	}
+
+	return messages
+}
+
+// Known 返回可订阅的围栏名称（本地围栏与见过的 TeslaMate 围栏）
+func (g *GeofenceTracker) Known() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	names := slices.Clone(g.state.Seen)
+	for _, fence := range g.local {
+		if !slices.Contains(names, fence.Name) {
+			names = append(names, fence.Name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Places 返回指定状态下车辆所在的围栏
+func (g *GeofenceTracker) Places(status *models.CarStatus) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.currentPlaces(status)
+}
+
+// currentPlaces 计算车辆当前所在的全部围栏（结果已排序）
+func (g *GeofenceTracker) currentPlaces(status *models.CarStatus) []string {
+	var places []string
+	if name := status.CarGeodata.Geofence; name != "" {
+		places = append(places, name)
+	}
+
+	lat, lon := carPosition(status)
+	if lat == 0 && lon == 0 {
+		// 没有坐标时保持本地围栏的原状态
+		for _, fence := range g.local {
+			if slices.Contains(g.state.Inside, fence.Name) && !slices.Contains(places, fence.Name) {
+				places = append(places, fence.Name)
+			}
+		}
+	} else {
+		for _, fence := range g.local {
+			radius := fence.Radius
+			if slices.Contains(g.state.Inside, fence.Name) {
+				radius *= geofenceExitFactor
+			}
+			if distanceMeters(lat, lon, fence.Latitude, fence.Longitude) <= radius && !slices.Contains(places, fence.Name) {
+				places = append(places, fence.Name)
+			}
+		}
+	}
+
+	slices.Sort(places)
+	return places
+}
+
+// carPosition 返回车辆坐标，优先使用 car_geodata 顶层坐标
+func carPosition(status *models.CarStatus) (float64, float64) {
+	geo := status.CarGeodata
+	if geo.Latitude != 0 || geo.Longitude != 0 {
+		return geo.Latitude, geo.Longitude
+	}
+	return geo.Location.Latitude, geo.Location.Longitude
+}
+
+// distanceMeters 计算两点间的球面距离（米）
+func distanceMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
+
+// geofenceTopic 围栏对应的推送主题（默认不订阅）
+func geofenceTopic(name string) string {
+	return "geofence:" + name
+}
+
+// geofenceID 围栏名称的短标识，用于回调数据（Telegram 限制 64 字节）
+func geofenceID(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// formatGeofenceEvent 格式化围栏到达/离开通知
+func formatGeofenceEvent(action, name string, status *models.CarStatus) string {
+	return fmt.Sprintf(
+		"%s %s\n"+
+			"━━━━━━━━━━━━━━━━━━━━\n"+
+			"🚗 %s\n"+
+			"🔋 电量: %d%%",
+		action,
+		name,
+		status.DisplayName,
+		status.BatteryDetails.BatteryLevel,
+	)
+}
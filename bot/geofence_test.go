@@ -0,0 +1,76 @@
+package bot
+
+import (
+	"math"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"teslamate-bot/config"
+	"teslamate-bot/models"
+)
+
+// officeFence 测试用本地围栏，半径 500 米
+var officeFence = config.GeofenceConfig{Name: "公司", Latitude: 31.2, Longitude: 121.5, Radius: 500}
+
+// metersPerDegree 纬度每度对应的距离（米）
+const metersPerDegree = earthRadiusMeters * math.Pi / 180
+
+// place 车辆位于 TeslaMate 围栏 geofence（可为空）、本地围栏正北 north 米处
+func place(geofence string, north float64) func(s *models.CarStatus) {
+	return func(s *models.CarStatus) {
+		lat := officeFence.Latitude + north/metersPerDegree
+		s.CarGeodata = models.CarGeodata{Geofence: geofence, Latitude: lat, Longitude: officeFence.Longitude}
+	}
+}
+
+// noPosition 没有坐标（如车辆离线）
+func noPosition(s *models.CarStatus) {
+	s.CarGeodata = models.CarGeodata{}
+}
+
+func TestGeofenceTrackerExitRadius(t *testing.T) {
+	g := NewGeofenceTracker(1, []config.GeofenceConfig{officeFence}, newTestStore(t))
+
+	runSteps(t, g, []checkStep{
+		// 首次运行只记录基线
+		{status: testStatus(t, place("", 0))},
+		// 半径与 1.2 倍半径之间仍视为在围栏内
+		{status: testStatus(t, place("", 550))},
+		{status: testStatus(t, noPosition)},
+		{status: testStatus(t, place("", 590))},
+		{status: testStatus(t, place("", 610)), want: []string{"geofence:公司: 🚗 已离开 公司"}},
+		// 离开后需回到半径以内才算到达
+		{status: testStatus(t, place("", 550))},
+		{status: testStatus(t, noPosition)},
+		{status: testStatus(t, place("", 490)), want: []string{"geofence:公司: 📍 已到达 公司"}},
+	})
+}
+
+func TestGeofenceTrackerTeslaMate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "teslamate-bot.db")
+	st := openTestStore(t, path)
+
+	g := NewGeofenceTracker(1, []config.GeofenceConfig{officeFence}, st)
+	runSteps(t, g, []checkStep{
+		{status: testStatus(t, place("", 5000))},
+		{status: testStatus(t, place("家", 5000)), want: []string{"geofence:家: 📍 已到达 家"}},
+		{status: testStatus(t, place("家", 0)), want: []string{"geofence:公司: 📍 已到达 公司"}},
+		{status: testStatus(t, place("", 5000)), want: []string{
+			"geofence:公司: 🚗 已离开 公司",
+			"geofence:家: 🚗 已离开 家",
+		}},
+	})
+	if got, want := g.Known(), []string{"公司", "家"}; !slices.Equal(got, want) {
+		t.Errorf("Known() = %q, 期望 %q", got, want)
+	}
+
+	// 重启后保留已见过的围栏与所在围栏
+	runSteps(t, g, []checkStep{{status: testStatus(t, place("家", 5000)), want: []string{"geofence:家: 📍 已到达 家"}}})
+	st.Close()
+	g = NewGeofenceTracker(1, []config.GeofenceConfig{officeFence}, openTestStore(t, path))
+	runSteps(t, g, []checkStep{{status: testStatus(t, place("家", 5000))}})
+	if got, want := g.Known(), []string{"公司", "家"}; !slices.Equal(got, want) {
+		t.Errorf("重启后 Known() = %q, 期望 %q", got, want)
+	}
+}
@@ -1,19 +1,28 @@
 package bot
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"strings"
+	"time"
 
 	"teslamate-bot/client"
+	"teslamate-bot/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// versionHistoryLimit /version 中显示的更新记录条数
+const versionHistoryLimit = 5
+
 // Handler 处理器结构
 type Handler struct {
-	client *client.Client
+	client client.TeslaMateAPI
 }
 
 // NewHandler 创建新的处理器
-func NewHandler(tmClient *client.Client) *Handler {
+func NewHandler(tmClient client.TeslaMateAPI) *Handler {
 	return &Handler{
 		client: tmClient,
 	}
@@ -34,12 +43,201 @@ func (h *Handler) HandleHelp() string {
 		"/battery - 查看电池健康度\n" +
 		"/charge - 查看最新充电记录\n" +
 		"/drive - 查看最近一次驾驶信息\n" +
+		"/tires - 查看胎压\n" +
+		"/version - 查看软件版本与更新记录\n" +
+		"/drain - 查看停车掉电情况\n" +
+		"/location - 查看车辆位置\n" +
+		"/control - 远程控制车辆（需确认）\n" +
+		"/cars - 查看车辆列表并切换当前车辆\n" +
+		"/notify - 设置推送通知\n" +
+		"/alerts - 设置电量提醒\n" +
+		"/geofences - 设置围栏到达/离开通知\n" +
+		"/auth - 进行二次验证\n" +
+		"/lock - 结束二次验证有效期\n" +
+		"/users - 查看授权列表（管理员）\n" +
+		"/invite - 生成邀请码（管理员）\n" +
+		"/allow - 授权会话或用户（管理员）\n" +
+		"/deny - 移除授权（管理员）\n" +
 		"/help - 显示帮助信息"
 }
 
+// HandleDenied 权限不足提示
+func (h *Handler) HandleDenied(required, current Role) string {
+	return fmt.Sprintf("⛔ 权限不足\n该操作需要「%s」权限，您当前为「%s」", required, current)
+}
+
+// HandleUsers 处理/users命令
+func (h *Handler) HandleUsers(chats, users []AccessEntry) string {
+	lines := []string{
+		"👥 授权列表",
+		"━━━━━━━━━━━━━━━━━━━━",
+		"💬 会话:",
+	}
+	lines = append(lines, formatAccessEntries(chats)...)
+	lines = append(lines, "", "👤 用户:")
+	lines = append(lines, formatAccessEntries(users)...)
+	lines = append(lines,
+		"━━━━━━━━━━━━━━━━━━━━",
+		"⚙️ 配置文件 | 🔧 运行时添加",
+		"/allow <会话ID> [角色] 授权会话",
+		"/allow user <用户ID> [角色] 授权用户",
+		"/deny <ID> 移除授权",
+	)
+	return strings.Join(lines, "\n")
+}
+
+// formatAccessEntries 格式化授权记录
+func formatAccessEntries(entries []AccessEntry) []string {
+	if len(entries) == 0 {
+		return []string{"  无"}
+	}
+	lines := make([]string, 0, len(entries))
+	for _, e := range entries {
+		source := "⚙️"
+		if e.Runtime {
+			source = "🔧"
+		}
+		lines = append(lines, fmt.Sprintf("  %s %d（%s）", source, e.ID, e.Role))
+	}
+	return lines
+}
+
+// HandleAccessAttempt 未授权访问提醒（推送给管理员）
+func (h *Handler) HandleAccessAttempt(chat *tgbotapi.Chat, user *tgbotapi.User, text string) string {
+	if runes := []rune(text); len(runes) > 100 {
+		text = string(runes[:100]) + "…"
+	}
+
+	return fmt.Sprintf("🚪 未授权访问尝试\n━━━━━━━━━━━━━━━━━━━━\n%s\n📝 内容: %s\n━━━━━━━━━━━━━━━━━━━━\n授权该会话: /allow %d viewer",
+		formatChatUser(chat, user), text, chat.ID)
+}
+
+// HandleInvite 处理/invite命令（生成邀请码后的说明）
+func (h *Handler) HandleInvite(botName, code string, role Role, expiresAt time.Time) string {
+	return fmt.Sprintf("🎟️ 邀请码已生成（单次有效）\n━━━━━━━━━━━━━━━━━━━━\n"+
+		"👤 角色: %s\n⏰ 有效期至: %s\n━━━━━━━━━━━━━━━━━━━━\n"+
+		"私聊: https://t.me/%s?start=%s\n"+
+		"群组: https://t.me/%s?startgroup=%s\n"+
+		"或在会话中发送: /start %s",
+		role, expiresAt.Format("2006-01-02 15:04"), botName, code, botName, code, code)
+}
+
+// HandleInviteJoined 邀请码被使用后通知创建者
+func (h *Handler) HandleInviteJoined(chat *tgbotapi.Chat, user *tgbotapi.User, role Role) string {
+	return fmt.Sprintf("🎉 新会话已通过邀请加入\n━━━━━━━━━━━━━━━━━━━━\n%s\n🔑 角色: %s\n━━━━━━━━━━━━━━━━━━━━\n移除授权: /deny %d",
+		formatChatUser(chat, user), role, chat.ID)
+}
+
+// formatChatUser 格式化会话与用户信息
+func formatChatUser(chat *tgbotapi.Chat, user *tgbotapi.User) string {
+	chatName := chat.Title
+	if chatName == "" {
+		chatName = strings.TrimSpace(chat.FirstName + " " + chat.LastName)
+	}
+
+	userLine := "未知"
+	if user != nil {
+		userLine = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		if user.UserName != "" {
+			userLine += " @" + user.UserName
+		}
+		userLine += fmt.Sprintf("（ID: %d）", user.ID)
+	}
+
+	return fmt.Sprintf("💬 会话: %s（ID: %d，%s）\n👤 用户: %s", chatName, chat.ID, chat.Type, userLine)
+}
+
+// HandleAuthPrompt 二次验证提示
+func (h *Handler) HandleAuthPrompt(group bool) string {
+	text := fmt.Sprintf("🔐 该操作需要二次验证\n请在 %s内发送 PIN 或验证器应用中的 6 位动态码", formatDuration(authPendingTTL))
+	if group {
+		text += "\n⚠️ 建议在与Bot的私聊中验证，群组中的验证码消息会尽量自动删除"
+	}
+	return text
+}
+
+// HandleCars 处理/cars命令
+func (h *Handler) HandleCars(cars []models.Car, activeCarID int) string {
+	lines := []string{
+		"🚘 车辆列表",
+		"━━━━━━━━━━━━━━━━━━━━",
+	}
+	for _, car := range cars {
+		mark := "  "
+		if car.CarID == activeCarID {
+			mark = "👉"
+		}
+		lines = append(lines, fmt.Sprintf("%s #%d %s", mark, car.CarID, carName(car)))
+	}
+	lines = append(lines, "━━━━━━━━━━━━━━━━━━━━", "点击下方按钮切换当前会话使用的车辆：")
+	return strings.Join(lines, "\n")
+}
+
+// carName 车辆显示名称（未命名时使用车辆ID）
+func carName(car models.Car) string {
+	if car.Name != "" {
+		return car.Name
+	}
+	return fmt.Sprintf("车辆 #%d", car.CarID)
+}
+
+// HandleControl 处理/control命令
+func (h *Handler) HandleControl(car models.Car) string {
+	return "🎮 远程控制\n" +
+		"━━━━━━━━━━━━━━━━━━━━\n" +
+		"🚗 " + carName(car) + "\n" +
+		"━━━━━━━━━━━━━━━━━━━━\n" +
+		"请选择要执行的操作，执行前需要再次确认。\n" +
+		"车辆休眠时请先唤醒车辆。"
+}
+
+// HandleControlConfirm 远程操作确认提示
+func (h *Handler) HandleControlConfirm(car models.Car, action controlAction) string {
+	return fmt.Sprintf("⚠️ 确认执行远程操作？\n━━━━━━━━━━━━━━━━━━━━\n🚗 %s\n👉 %s", carName(car), action.Label)
+}
+
+// HandleControlCommand 执行远程操作并返回结果
+func (h *Handler) HandleControlCommand(ctx context.Context, car models.Car, action controlAction) string {
+	if err := h.client.SendCommand(ctx, car.CarID, action.Command, action.Payload); err != nil {
+		log.Printf("远程操作失败 (CarID: %d, %s): %v", car.CarID, action.Command, err)
+		return fmt.Sprintf("❌ 执行失败\n━━━━━━━━━━━━━━━━━━━━\n🚗 %s\n👉 %s\n%s", carName(car), action.Label, errorText(err))
+	}
+	return fmt.Sprintf("✅ 已执行\n━━━━━━━━━━━━━━━━━━━━\n🚗 %s\n👉 %s", carName(car), action.Label)
+}
+
+// HandleNotify 处理/notify命令
+func (h *Handler) HandleNotify() string {
+	return "🔔 推送通知设置\n\n" +
+		"点击下方按钮开启或关闭当前会话的推送："
+}
+
+// HandleAlerts 处理/alerts命令
+func (h *Handler) HandleAlerts(settings ChatAlerts) string {
+	return fmt.Sprintf(
+		"🔔 电量提醒设置\n"+
+			"━━━━━━━━━━━━━━━━━━━━\n"+
+			"🪫 低电量提醒: %s\n"+
+			"🎯 充电目标提醒: %s\n"+
+			"━━━━━━━━━━━━━━━━━━━━\n"+
+			"第一行按钮设置低电量阈值（未插枪时电量低于该值提醒），"+
+			"第二行设置充电目标电量（充电达到该值时提醒）。\n"+
+			"也可使用 /alerts low 15 或 /alerts target 85 设置任意值。",
+		formatThreshold(settings.LowBattery),
+		formatThreshold(settings.TargetSOC),
+	)
+}
+
+// formatThreshold 格式化电量阈值
+func formatThreshold(level int) string {
+	if level <= 0 {
+		return "关闭"
+	}
+	return fmt.Sprintf("%d%%", level)
+}
+
 // HandleInfo 处理车辆信息请求
-func (h *Handler) HandleInfo() (string, error) {
-	car, err := h.client.GetCarDetails()
+func (h *Handler) HandleInfo(ctx context.Context, carID int) (string, error) {
+	car, err := h.client.GetCarDetails(ctx, carID)
 	if err != nil {
 		return "", err
 	}
@@ -72,8 +270,8 @@ func (h *Handler) HandleInfo() (string, error) {
 }
 
 // HandleStatus 处理车辆状态请求
-func (h *Handler) HandleStatus() (string, error) {
-	statusResp, err := h.client.GetCarStatus()
+func (h *Handler) HandleStatus(ctx context.Context, carID int) (string, error) {
+	statusResp, err := h.client.GetCarStatus(ctx, carID)
 	if err != nil {
 		return "", err
 	}
@@ -149,9 +347,140 @@ func (h *Handler) HandleStatus() (string, error) {
 	), nil
 }
 
+// HandleTires 处理胎压请求
+func (h *Handler) HandleTires(ctx context.Context, carID int) (string, error) {
+	statusResp, err := h.client.GetCarStatus(ctx, carID)
+	if err != nil {
+		return "", err
+	}
+
+	status := statusResp.Data.Status
+	readings := tireReadings(status.TPMSDetails)
+
+	warning := "✅ 无胎压警告"
+	for _, r := range readings {
+		if r.Warning {
+			warning = "⚠️ 存在胎压警告，请检查标记的轮胎"
+			break
+		}
+	}
+
+	return fmt.Sprintf(
+		"🛞 胎压监测\n"+
+			"━━━━━━━━━━━━━━━━━━━━\n"+
+			"🚗 %s\n"+
+			"%s\n"+
+			"━━━━━━━━━━━━━━━━━━━━\n"+
+			"%s\n"+
+			"⏰ 状态更新: %s",
+		status.DisplayName,
+		formatTires(readings, statusResp.Data.Units.UnitOfPressure),
+		warning,
+		formatDateTime(status.StateSince),
+	), nil
+}
+
+// HandleVersion 处理软件版本请求
+func (h *Handler) HandleVersion(ctx context.Context, carID int) (string, error) {
+	statusResp, err := h.client.GetCarStatus(ctx, carID)
+	if err != nil {
+		return "", err
+	}
+
+	status := statusResp.Data.Status
+	versions := status.CarVersions
+
+	pending := "无"
+	if versions.UpdateAvailable {
+		pending = versions.UpdateVersion
+		if pending == "" {
+			pending = "有可用更新"
+		}
+	}
+
+	history := "  暂无更新记录"
+	updates, err := h.client.GetUpdates(ctx, carID)
+	if err != nil {
+		log.Printf("获取软件更新记录失败 (CarID: %d): %v", carID, err)
+		history = fmt.Sprintf("  ❌ %s", errorText(err))
+	} else if len(updates) > 0 {
+		lines := make([]string, 0, versionHistoryLimit)
+		for i, u := range updates {
+			if i >= versionHistoryLimit {
+				break
+			}
+			date, _ := splitDateTime(u.StartDate)
+			lines = append(lines, fmt.Sprintf("  📦 %s (%s)", u.Version, date))
+		}
+		history = strings.Join(lines, "\n")
+	}
+
+	return fmt.Sprintf(
+		"📲 软件版本\n"+
+			"━━━━━━━━━━━━━━━━━━━━\n"+
+			"🚗 %s\n"+
+			"📦 当前版本: %s\n"+
+			"⬆️ 待安装更新: %s\n"+
+			"━━━━━━━━━━━━━━━━━━━━\n"+
+			"🕘 最近更新记录:\n"+
+			"%s",
+		status.DisplayName,
+		versions.Version,
+		pending,
+		history,
+	), nil
+}
+
+// HandleLocation 处理车辆位置请求
+func (h *Handler) HandleLocation(ctx context.Context, carID int, geofences *GeofenceTracker) (string, error) {
+	statusResp, err := h.client.GetCarStatus(ctx, carID)
+	if err != nil {
+		return "", err
+	}
+
+	status := statusResp.Data.Status
+	lat, lon := carPosition(&status)
+
+	places := "无"
+	if names := geofences.Places(&status); len(names) > 0 {
+		places = strings.Join(names, "、")
+	}
+
+	return fmt.Sprintf(
+		"📍 车辆位置\n"+
+			"━━━━━━━━━━━━━━━━━━━━\n"+
+			"🚗 %s\n"+
+			"🏷️ 所在围栏: %s\n"+
+			"🌐 坐标: %.6f, %.6f\n"+
+			"🗺️ 地图: https://maps.google.com/?q=%.6f,%.6f\n"+
+			"⏰ 状态更新: %s",
+		status.DisplayName,
+		places,
+		lat, lon,
+		lat, lon,
+		formatDateTime(status.StateSince),
+	), nil
+}
+
+// HandleGeofences 处理/geofences命令
+func (h *Handler) HandleGeofences(known []string) string {
+	if len(known) == 0 {
+		return "📍 围栏通知设置\n\n" +
+			"暂无可订阅的围栏。车辆进入 TeslaMate 中的围栏后会出现在这里，" +
+			"也可在 config.toml 的 [[monitor.geofences]] 中添加本地围栏。"
+	}
+	return "📍 围栏通知设置\n\n" +
+		"点击围栏开启或关闭当前会话的到达/离开通知："
+}
+
+// HandleDrain 处理停车掉电请求
+func (h *Handler) HandleDrain(ctx context.Context, drain *DrainTracker) string {
+	return drain.Report(ctx)
+}
+
 // HandleBattery 处理电池健康度请求
-func (h *Handler) HandleBattery() (string, error) {
-	batteryResp, err := h.client.GetBatteryHealth()
+func (h *Handler) HandleBattery(ctx context.Context, carID int) (string, error) {
+	batteryResp, err := h.client.GetBatteryHealth(ctx, carID)
 	if err != nil {
 		return "", err
 	}
@@ -193,8 +522,8 @@ func (h *Handler) HandleBattery() (string, error) {
 }
 
 // HandleCharge 处理最新充电记录请求
-func (h *Handler) HandleCharge() (string, error) {
-	charge, err := h.client.GetLatestCharge()
+func (h *Handler) HandleCharge(ctx context.Context, carID int) (string, error) {
+	charge, err := h.client.GetLatestCharge(ctx, carID)
 	if err != nil {
 		return "", err
 	}
@@ -230,22 +559,29 @@ func (h *Handler) HandleCharge() (string, error) {
 }
 
 // HandleDrive 处理最近一次驾驶信息请求
-func (h *Handler) HandleDrive() (string, error) {
-	drive, units, err := h.client.GetLatestDrive()
+func (h *Handler) HandleDrive(ctx context.Context, carID int) (string, error) {
+	drive, units, err := h.client.GetLatestDrive(ctx, carID)
 	if err != nil {
 		return "", err
 	}
 
+	return formatDrive("🚗 最近一次驾驶", drive, units), nil
+}
+
+// formatDrive 格式化驾驶记录（/drive 与行程结束推送共用）
+func formatDrive(title string, drive *models.Drive, units *models.Units) string {
 	startDate, startTime := splitDateTime(drive.StartDate)
 	endTime := extractTime(drive.EndDate)
 
 	return fmt.Sprintf(
-		"🚗 最近一次驾驶\n"+
+		"%s\n"+
 			"━━━━━━━━━━━━━━━━━━━━\n"+
 			"📅 日期: %s\n"+
 			"🕐 开始: %s\n"+
 			"🕐 结束: %s\n"+
 			"⏱️ 时长: %s\n"+
+			"📍 起点: %s\n"+
+			"🏁 终点: %s\n"+
 			"📏 里程: %.2f %s\n"+
 			"📊 表显: %.2f → %.2f %s\n"+
 			"🔋 电量: %d%% → %d%%\n"+
@@ -253,10 +589,13 @@ func (h *Handler) HandleDrive() (string, error) {
 			"⚡ 能耗: %.2f kWh (%.0f Wh/%s)\n"+
 			"🌡️ 车外/车内: %.1f°%s / %.1f°%s\n"+
 			"🚀 最高速度: %.0f %s/h | 平均: %.0f %s/h",
+		title,
 		startDate,
 		startTime,
 		endTime,
 		drive.DurationStr,
+		formatAddress(drive.StartAddress),
+		formatAddress(drive.EndAddress),
 		drive.OdometerDetails.OdometerDistance,
 		units.UnitOfLength,
 		drive.OdometerDetails.OdometerStart,
@@ -278,7 +617,15 @@ func (h *Handler) HandleDrive() (string, error) {
 		units.UnitOfLength,
 		drive.SpeedAvg,
 		units.UnitOfLength,
-	), nil
+	)
+}
+
+// formatAddress 格式化地址，为空时显示"未知"
+func formatAddress(address string) string {
+	if address == "" {
+		return "未知"
+	}
+	return address
 }
 
 // formatDateTime 格式化日期时间
@@ -0,0 +1,248 @@
+package bot
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"teslamate-bot/client/teslamatetest"
+	"teslamate-bot/config"
+	"teslamate-bot/models"
+	"teslamate-bot/store"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+var update = flag.Bool("update", false, "用当前输出更新 testdata/golden 中的文件")
+
+func TestMain(m *testing.M) {
+	// 报告中的本地时间使用 UTC，保证输出与运行环境无关
+	time.Local = time.UTC
+	flag.Parse()
+	os.Exit(m.Run())
+}
+
+// assertGolden 比较输出与 testdata/golden/<name>.golden，-update 时写入
+func assertGolden(t *testing.T, name, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", "golden", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取 golden 文件失败（可使用 -update 生成）: %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s 输出与 golden 文件不一致\n--- 实际 ---\n%s\n--- 期望 ---\n%s", name, got, want)
+	}
+}
+
+// renderResult 与 Bot 展示查询结果的方式一致：出错时显示错误提示
+func renderResult(text string, err error) string {
+	if err != nil {
+		return "❌ " + errorText(err)
+	}
+	return text
+}
+
+// newTestStore 创建临时数据库
+func newTestStore(t *testing.T) *store.Store {
+	t.Helper()
+	return openTestStore(t, filepath.Join(t.TempDir(), "teslamate-bot.db"))
+}
+
+// openTestStore 打开指定路径的数据库（用于模拟重启后重新打开）
+func openTestStore(t *testing.T, path string) *store.Store {
+	t.Helper()
+	st, err := store.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+func TestHandlerQueries(t *testing.T) {
+	srv := teslamatetest.NewServer()
+	defer srv.Close()
+	srv.Handle("/api/v1/cars/3", http.StatusUnauthorized, `{"error":"invalid token"}`)
+	srv.Handle("/api/v1/cars/3/status", http.StatusBadGateway, "<html>Bad gateway</html>")
+	srv.Handle("/api/v1/cars/3/battery-health", http.StatusOK, `{"data":`)
+
+	h := NewHandler(srv.Client())
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		run  func() (string, error)
+	}{
+		{"info", func() (string, error) { return h.HandleInfo(ctx, 1) }},
+		{"info_nulls", func() (string, error) { return h.HandleInfo(ctx, 2) }},
+		{"info_unauthorized", func() (string, error) { return h.HandleInfo(ctx, 3) }},
+		{"info_not_found", func() (string, error) { return h.HandleInfo(ctx, 9) }},
+		{"status", func() (string, error) { return h.HandleStatus(ctx, 1) }},
+		{"status_nulls", func() (string, error) { return h.HandleStatus(ctx, 2) }},
+		{"status_upstream", func() (string, error) { return h.HandleStatus(ctx, 3) }},
+		{"battery", func() (string, error) { return h.HandleBattery(ctx, 1) }},
+		{"battery_nulls", func() (string, error) { return h.HandleBattery(ctx, 2) }},
+		{"battery_decode", func() (string, error) { return h.HandleBattery(ctx, 3) }},
+		{"charge", func() (string, error) { return h.HandleCharge(ctx, 1) }},
+		{"charge_empty", func() (string, error) { return h.HandleCharge(ctx, 2) }},
+		{"drive", func() (string, error) { return h.HandleDrive(ctx, 1) }},
+		{"drive_empty", func() (string, error) { return h.HandleDrive(ctx, 2) }},
+		{"tires", func() (string, error) { return h.HandleTires(ctx, 1) }},
+		{"tires_nulls", func() (string, error) { return h.HandleTires(ctx, 2) }},
+		{"version", func() (string, error) { return h.HandleVersion(ctx, 1) }},
+		{"version_empty", func() (string, error) { return h.HandleVersion(ctx, 2) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertGolden(t, tt.name, renderResult(tt.run()))
+		})
+	}
+}
+
+func TestHandleVersionUpdatesError(t *testing.T) {
+	srv := teslamatetest.NewServer()
+	defer srv.Close()
+	srv.Handle("/api/v1/cars/1/updates", http.StatusServiceUnavailable, "upstream down")
+
+	text, err := NewHandler(srv.Client()).HandleVersion(context.Background(), 1)
+	assertGolden(t, "version_updates_error", renderResult(text, err))
+}
+
+func TestHandleLocation(t *testing.T) {
+	srv := teslamatetest.NewServer()
+	defer srv.Close()
+	h := NewHandler(srv.Client())
+	st := newTestStore(t)
+
+	local := []config.GeofenceConfig{
+		{Name: "小区", Latitude: 31.2305, Longitude: 121.4738, Radius: 200},
+		{Name: "公司", Latitude: 31.2397, Longitude: 121.4998, Radius: 100},
+	}
+	for _, carID := range []int{1, 2} {
+		text, err := h.HandleLocation(context.Background(), carID, NewGeofenceTracker(carID, local, st))
+		name := "location"
+		if carID == 2 {
+			name = "location_nulls"
+		}
+		assertGolden(t, name, renderResult(text, err))
+	}
+}
+
+func TestHandleDrain(t *testing.T) {
+	srv := teslamatetest.NewServer()
+	defer srv.Close()
+	h := NewHandler(srv.Client())
+	st := newTestStore(t)
+
+	empty := NewDrainTracker(1, config.DrainWatchConfig{}, time.UTC, srv.Client(), st)
+	assertGolden(t, "drain_empty", h.HandleDrain(context.Background(), empty))
+
+	err := st.Put(carKey(drainKey, 1), drainState{Last: &drainPeriod{
+		CarName:    "小白",
+		StartedAt:  "2024-04-30T18:00:00Z",
+		StartLevel: 80,
+		StartRange: 390,
+		LastAt:     "2024-05-01T06:00:00Z",
+		LastLevel:  78,
+		LastRange:  381,
+		Unit:       "km",
+		AsleepSec:  36000,
+		OnlineSec:  3600,
+		OfflineSec: 0,
+		SentrySec:  3600,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	drain := NewDrainTracker(1, config.DrainWatchConfig{}, time.UTC, srv.Client(), st)
+	assertGolden(t, "drain_last", h.HandleDrain(context.Background(), drain))
+}
+
+func TestHandleControlCommand(t *testing.T) {
+	srv := teslamatetest.NewServer()
+	defer srv.Close()
+	srv.HandleFixture("/api/v1/cars/1/command/set_sentry_mode", "command_rejected.json")
+	srv.Handle("/api/v1/cars/1/command/honk_horn", http.StatusRequestTimeout, `{"error":"vehicle unavailable: vehicle is offline or asleep"}`)
+
+	h := NewHandler(srv.Client())
+	car := models.Car{CarID: 1, Name: "小白"}
+
+	for _, key := range []string{"lock", "sentry_on", "honk"} {
+		action, ok := findControlAction(key)
+		if !ok {
+			t.Fatalf("找不到远程操作 %s", key)
+		}
+		assertGolden(t, "control_command_"+key, h.HandleControlCommand(context.Background(), car, action))
+	}
+
+	want := []string{
+		"POST /api/v1/cars/1/command/door_lock",
+		"POST /api/v1/cars/1/command/set_sentry_mode",
+		"POST /api/v1/cars/1/command/honk_horn",
+	}
+	got := srv.Requests()
+	if len(got) != len(want) {
+		t.Fatalf("请求 = %v, 期望 %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("第 %d 个请求 = %s, 期望 %s", i+1, got[i], want[i])
+		}
+	}
+}
+
+func TestHandlerStaticTexts(t *testing.T) {
+	h := NewHandler(nil)
+	cars := []models.Car{{CarID: 1, Name: "小白"}, {CarID: 2}}
+	lock, _ := findControlAction("lock")
+	chat := &tgbotapi.Chat{ID: -100123, Type: "supergroup", Title: "家庭群"}
+	user := &tgbotapi.User{ID: 42, FirstName: "Alex", UserName: "alex"}
+	expires := time.Date(2024, 5, 2, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"start", h.HandleStart()},
+		{"help", h.HandleHelp()},
+		{"denied", h.HandleDenied(RoleController, RoleViewer)},
+		{"users", h.HandleUsers(
+			[]AccessEntry{{ID: -100123, Role: RoleViewer}, {ID: 10, Role: RoleController, Runtime: true}},
+			[]AccessEntry{{ID: 42, Role: RoleAdmin}},
+		)},
+		{"users_empty", h.HandleUsers(nil, nil)},
+		{"access_attempt", h.HandleAccessAttempt(chat, user, "/status")},
+		{"invite", h.HandleInvite("tesla_bot", "a1b2c3d4e5f60718", RoleViewer, expires)},
+		{"invite_joined", h.HandleInviteJoined(&tgbotapi.Chat{ID: 42, Type: "private"}, user, RoleViewer)},
+		{"auth_prompt", h.HandleAuthPrompt(false)},
+		{"auth_prompt_group", h.HandleAuthPrompt(true)},
+		{"cars", h.HandleCars(cars, 2)},
+		{"control", h.HandleControl(cars[0])},
+		{"control_confirm", h.HandleControlConfirm(cars[1], lock)},
+		{"notify", h.HandleNotify()},
+		{"alerts", h.HandleAlerts(ChatAlerts{LowBattery: 20, TargetSOC: 0})},
+		{"geofences", h.HandleGeofences([]string{"家", "公司"})},
+		{"geofences_empty", h.HandleGeofences(nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertGolden(t, tt.name, tt.text)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"teslamate-bot/store"
+)
+
+const (
+	// invitesKey 邀请码在状态存储中的键
+	invitesKey = "invites"
+	// inviteDefaultTTL 邀请码默认有效期
+	inviteDefaultTTL = 24 * time.Hour
+	// inviteMaxTTL 邀请码最长有效期
+	inviteMaxTTL = 30 * 24 * time.Hour
+)
+
+// invite 一个单次有效的邀请码
+type invite struct {
+	Role      string `json:"role"`
+	CreatedBy int64  `json:"created_by"` // 创建邀请码的会话
+	ExpiresAt string `json:"expires_at"`
+}
+
+// Invites 管理员生成的邀请码
+type Invites struct {
+	mu    sync.Mutex
+	store *store.Store
+	codes map[string]invite
+}
+
+// NewInvites 从状态存储加载邀请码
+func NewInvites(st *store.Store) *Invites {
+	i := &Invites{
+		store: st,
+		codes: make(map[string]invite),
+	}
+	if _, err := st.Get(invitesKey, &i.codes); err != nil {
+		log.Printf("加载邀请码失败: %v", err)
+	}
+	return i
+}
+
+// Create 生成邀请码
+func (i *Invites) Create(role Role, createdBy int64, ttl time.Duration, now time.Time) (string, time.Time, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, fmt.Errorf("生成邀请码失败: %w", err)
+	}
+	code := hex.EncodeToString(buf)
+	expiresAt := now.Add(ttl)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.prune(now)
+	i.codes[code] = invite{Role: role.Name(), CreatedBy: createdBy, ExpiresAt: expiresAt.Format(time.RFC3339)}
+	i.save()
+	return code, expiresAt, nil
+}
+
+// Redeem 使用邀请码（成功后邀请码失效）
+func (i *Invites) Redeem(code string, now time.Time) (invite, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.prune(now)
+	inv, ok := i.codes[code]
+	if !ok {
+		return invite{}, false
+	}
+	delete(i.codes, code)
+	i.save()
+	return inv, true
+}
+
+// prune 清理过期的邀请码（调用方需持有锁）
+func (i *Invites) prune(now time.Time) {
+	for code, inv := range i.codes {
+		if expiresAt, err := time.Parse(time.RFC3339, inv.ExpiresAt); err != nil || !now.Before(expiresAt) {
+			delete(i.codes, code)
+		}
+	}
+}
+
+// save 保存全部邀请码（调用方需持有锁）
+func (i *Invites) save() {
+	if err := i.store.Put(invitesKey, i.codes); err != nil {
+		log.Printf("保存邀请码失败: %v", err)
+	}
+}
+
+// parseTTL 解析邀请码有效期（支持 30m、12h、7d）
+func parseTTL(s string) (time.Duration, error) {
+	var ttl time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		ttl = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if ttl, err = time.ParseDuration(s); err != nil {
+			return 0, err
+		}
+	}
+	if ttl <= 0 || ttl > inviteMaxTTL {
+		return 0, fmt.Errorf("有效期需在 0 到 %s 之间", formatDuration(inviteMaxTTL))
+	}
+	return ttl, nil
+}
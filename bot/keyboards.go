@@ -1,31 +1,181 @@
 package bot
 
 import (
+	"fmt"
+
+	"teslamate-bot/models"
+
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-// GetMainMenu 获取主菜单键盘
-func GetMainMenu() tgbotapi.InlineKeyboardMarkup {
+// GetMainMenu 获取主菜单键盘（车辆相关按钮带车辆ID，多车时显示切换车辆按钮）
+func GetMainMenu(carID int, multiCar bool) tgbotapi.InlineKeyboardMarkup {
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📋 车辆信息", carData("info", carID)),
+			tgbotapi.NewInlineKeyboardButtonData("⚡ 当前状态", carData("status", carID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔋 电池健康", carData("battery", carID)),
+			tgbotapi.NewInlineKeyboardButtonData("🔌 最新充电", carData("charge", carID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🚗 最近驾驶", carData("drive", carID)),
+			tgbotapi.NewInlineKeyboardButtonData("🛞 胎压", carData("tires", carID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📍 车辆位置", carData("location", carID)),
+			tgbotapi.NewInlineKeyboardButtonData("🏷️ 围栏通知", "geofences"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📲 软件版本", carData("version", carID)),
+			tgbotapi.NewInlineKeyboardButtonData("🔔 推送设置", "notify"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🧛 停车掉电", carData("drain", carID)),
+			tgbotapi.NewInlineKeyboardButtonData("🪫 电量提醒", "alerts"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🎮 远程控制", carData("control", carID)),
+		),
+	}
+	if multiCar {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🚘 切换车辆", "cars"),
+		))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// GetCarsMenu 获取车辆选择菜单
+func GetCarsMenu(cars []models.Car, activeCarID int) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, car := range cars {
+		label := fmt.Sprintf("🚗 %s (#%d)", carName(car), car.CarID)
+		if car.CarID == activeCarID {
+			label = "✅ " + label
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, carData("select_car", car.CarID)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🏠 主菜单", "back_main"),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// GetControlMenu 获取远程控制菜单（每行两个操作）
+func GetControlMenu(carID int) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i := 0; i < len(controlActions); i += 2 {
+		var row []tgbotapi.InlineKeyboardButton
+		for _, a := range controlActions[i:min(i+2, len(controlActions))] {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(a.Label, carData("ctl_"+a.Key, carID)))
+		}
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🏠 主菜单", "back_main"),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// GetControlConfirmMenu 获取远程操作确认菜单
+func GetControlConfirmMenu(key string, carID int) tgbotapi.InlineKeyboardMarkup {
 	return tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("📋 车辆信息", "info"),
-			tgbotapi.NewInlineKeyboardButtonData("⚡ 当前状态", "status"),
+			tgbotapi.NewInlineKeyboardButtonData("✅ 确认执行", carData("ctlok_"+key, carID)),
+			tgbotapi.NewInlineKeyboardButtonData("❌ 取消", carData("control", carID)),
 		),
+	)
+}
+
+// GetControlResultMenu 获取远程操作结果菜单
+func GetControlResultMenu(carID int) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("🔋 电池健康", "battery"),
-			tgbotapi.NewInlineKeyboardButtonData("🔌 最新充电", "charge"),
+			tgbotapi.NewInlineKeyboardButtonData("🎮 远程控制", carData("control", carID)),
+			tgbotapi.NewInlineKeyboardButtonData("🏠 主菜单", "back_main"),
 		),
+	)
+}
+
+// carData 生成带车辆ID的回调数据（如 status:2）
+func carData(action string, carID int) string {
+	return fmt.Sprintf("%s:%d", action, carID)
+}
+
+// GetNotifyMenu 获取推送设置菜单（显示当前会话各主题的开关状态）
+func GetNotifyMenu(chatID int64, prefs *Preferences) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, t := range notifyTopics {
+		mark := "⬜"
+		if prefs.Enabled(chatID, t.Key) {
+			mark = "✅"
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(mark+" "+t.Name, "notify_"+t.Key),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🏠 主菜单", "back_main"),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// GetGeofenceMenu 获取围栏通知设置菜单
+func GetGeofenceMenu(chatID int64, prefs *Preferences, known []string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, name := range known {
+		mark := "⬜"
+		if prefs.Enabled(chatID, geofenceTopic(name)) {
+			mark = "✅"
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(mark+" "+name, "geofence_"+geofenceID(name)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🏠 主菜单", "back_main"),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// GetAlertsMenu 获取电量提醒设置菜单（第一行为低电量阈值，第二行为目标电量）
+func GetAlertsMenu(settings ChatAlerts) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		thresholdRow("🪫", "alerts_low_", lowBatteryOptions, settings.LowBattery),
+		thresholdRow("🎯", "alerts_target_", targetSOCOptions, settings.TargetSOC),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("🚗 最近驾驶", "drive"),
+			tgbotapi.NewInlineKeyboardButtonData("🏠 主菜单", "back_main"),
 		),
 	)
 }
 
+// thresholdRow 生成一行阈值选项按钮，当前选中的值加✅标记
+func thresholdRow(icon, prefix string, options []int, current int) []tgbotapi.InlineKeyboardButton {
+	buttons := make([]tgbotapi.InlineKeyboardButton, 0, len(options))
+	for _, v := range options {
+		label := "关闭"
+		if v > 0 {
+			label = fmt.Sprintf("%d%%", v)
+		}
+		if v == current {
+			label = "✅" + label
+		} else {
+			label = icon + label
+		}
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("%s%d", prefix, v)))
+	}
+	return buttons
+}
+
 // GetRefreshMenu 获取刷新菜单（带返回按钮）
-func GetRefreshMenu(refreshType string) tgbotapi.InlineKeyboardMarkup {
+func GetRefreshMenu(refreshType string, carID int) tgbotapi.InlineKeyboardMarkup {
 	return tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("🔄 刷新", "refresh_"+refreshType),
+			tgbotapi.NewInlineKeyboardButtonData("🔄 刷新", carData("refresh_"+refreshType, carID)),
 			tgbotapi.NewInlineKeyboardButtonData("🏠 主菜单", "back_main"),
 		),
 	)
@@ -0,0 +1,16 @@
+package bot
+
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Messenger Bot 向 Telegram 发送内容使用的接口（*tgbotapi.BotAPI 已实现）
+//
+// Send 用于发送和编辑消息，Request 用于回调应答、删除消息和注册命令等
+// 不返回消息的请求。接收更新仍直接使用 *tgbotapi.BotAPI。
+type Messenger interface {
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+	Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+}
+
+var _ Messenger = (*tgbotapi.BotAPI)(nil)
@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// recordingMessenger 记录全部发送内容的 Messenger
+type recordingMessenger struct {
+	mu     sync.Mutex
+	sent   []tgbotapi.Chattable
+	nextID int
+}
+
+// Send 记录消息，新消息分配递增的消息ID，编辑消息沿用原消息ID
+func (m *recordingMessenger) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sent = append(m.sent, c)
+	switch v := c.(type) {
+	case tgbotapi.MessageConfig:
+		m.nextID++
+		return tgbotapi.Message{MessageID: m.nextID, Chat: &tgbotapi.Chat{ID: v.ChatID}, Text: v.Text}, nil
+	case tgbotapi.EditMessageTextConfig:
+		return tgbotapi.Message{MessageID: v.MessageID, Chat: &tgbotapi.Chat{ID: v.ChatID}, Text: v.Text}, nil
+	}
+	return tgbotapi.Message{}, nil
+}
+
+// Request 记录请求（回调应答、删除消息、注册命令等）
+func (m *recordingMessenger) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sent = append(m.sent, c)
+	return &tgbotapi.APIResponse{Ok: true}, nil
+}
+
+// Take 取出并清空已记录的内容
+func (m *recordingMessenger) Take() []tgbotapi.Chattable {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sent := m.sent
+	m.sent = nil
+	return sent
+}
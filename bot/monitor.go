@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"teslamate-bot/client"
+	"teslamate-bot/models"
+)
+
+// Notification 后台监控产生的推送消息
+type Notification struct {
+	Topic  string // 推送主题，会话可在 /notify 中按主题开关
+	ChatID int64  // 非0时只推送给指定会话（忽略主题）
+	Text   string
+}
+
+// Watcher 后台监控项，根据每次轮询得到的车辆状态决定是否推送消息
+type Watcher interface {
+	// Name 监控项名称（含车辆ID），同时作为状态存储的键
+	Name() string
+	// Check 检查最新状态，返回需要推送的消息
+	Check(ctx context.Context, status *models.StatusResponse) []Notification
+}
+
+// carWatchers 单辆车的监控项
+type carWatchers struct {
+	carID    int
+	watchers []Watcher
+}
+
+// Monitor 后台轮询器，定期获取各车辆状态并交给对应的监控项处理
+type Monitor struct {
+	client   client.TeslaMateAPI
+	interval time.Duration
+	cars     []carWatchers
+	notify   func(n Notification)
+}
+
+// NewMonitor 创建后台轮询器
+func NewMonitor(tmClient client.TeslaMateAPI, interval time.Duration, notify func(n Notification)) *Monitor {
+	return &Monitor{
+		client:   tmClient,
+		interval: interval,
+		notify:   notify,
+	}
+}
+
+// AddCar 添加一辆车及其监控项
+func (m *Monitor) AddCar(carID int, watchers ...Watcher) {
+	m.cars = append(m.cars, carWatchers{carID: carID, watchers: watchers})
+}
+
+// carKey 生成带车辆ID的状态存储键
+func carKey(name string, carID int) string {
+	return fmt.Sprintf("%s:%d", name, carID)
+}
+
+// Run 启动轮询，直到 stop 被关闭（不再开始新的轮询，进行中的轮询会完成）
+//
+// ctx 用于进行中的请求，取消时中断请求。
+func (m *Monitor) Run(ctx context.Context, stop <-chan struct{}) {
+	log.Printf("后台监控已启动（车辆数: %d，轮询间隔: %s）", len(m.cars), m.interval)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	m.poll(ctx)
+	for {
+		select {
+		case <-stop:
+			log.Println("后台监控已停止")
+			return
+		case <-ticker.C:
+			m.poll(ctx)
+		}
+	}
+}
+
+// poll 执行一次轮询
+func (m *Monitor) poll(ctx context.Context) {
+	// 监控需要最新数据，获取到的数据同时更新缓存供查询使用
+	ctx = client.WithoutCache(ctx)
+	for _, car := range m.cars {
+		status, err := m.client.GetCarStatus(ctx, car.carID)
+		if err != nil {
+			log.Printf("后台监控获取车辆状态失败 (CarID: %d): %v", car.carID, err)
+			continue
+		}
+
+		for _, w := range car.watchers {
+			for _, n := range w.Check(ctx, status) {
+				m.notify(n)
+			}
+		}
+	}
+}
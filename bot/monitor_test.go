@@ -0,0 +1,177 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"teslamate-bot/client"
+	"teslamate-bot/client/teslamatetest"
+	"teslamate-bot/models"
+)
+
+// testStatus 以 fixture 中车辆 1 的状态为基础，依次应用修改
+func testStatus(t *testing.T, edits ...func(s *models.CarStatus)) *models.StatusResponse {
+	t.Helper()
+	var resp models.StatusResponse
+	if err := json.Unmarshal(teslamatetest.Fixture("status.json"), &resp); err != nil {
+		t.Fatal(err)
+	}
+	for _, edit := range edits {
+		edit(&resp.Data.Status)
+	}
+	return &resp
+}
+
+// charging 正在充电
+func charging(level int, energy float64) func(s *models.CarStatus) {
+	return func(s *models.CarStatus) {
+		s.ChargingDetails.PluggedIn = true
+		s.ChargingDetails.ChargingState = "Charging"
+		s.ChargingDetails.ChargeEnergyAdded = energy
+		s.BatteryDetails.BatteryLevel = level
+	}
+}
+
+// chargeState 已插枪，充电状态为 state（如 Complete、Stopped）
+func chargeState(state string, level int) func(s *models.CarStatus) {
+	return func(s *models.CarStatus) {
+		s.ChargingDetails.PluggedIn = true
+		s.ChargingDetails.ChargingState = state
+		s.BatteryDetails.BatteryLevel = level
+	}
+}
+
+// unplugged 未插枪
+func unplugged(s *models.CarStatus) {
+	s.ChargingDetails.PluggedIn = false
+	s.ChargingDetails.ChargingState = "Disconnected"
+	s.ChargingDetails.ChargeEnergyAdded = 0
+}
+
+// titles 每条通知的主题（指定会话时为会话ID）与第一行（标题）
+func titles(ns []Notification) []string {
+	var out []string
+	for _, n := range ns {
+		title, _, _ := strings.Cut(n.Text, "\n")
+		key := n.Topic
+		if n.ChatID != 0 {
+			key = strconv.FormatInt(n.ChatID, 10)
+		}
+		out = append(out, key+": "+title)
+	}
+	return out
+}
+
+// checkStep 监控项一次检查的输入与期望通知
+type checkStep struct {
+	status *models.StatusResponse
+	want   []string // titles 的结果，nil 表示不推送
+}
+
+// runSteps 依次检查状态序列并比较每一步的通知
+func runSteps(t *testing.T, w Watcher, steps []checkStep) {
+	t.Helper()
+	// 与后台轮询一致，不使用缓存
+	ctx := client.WithoutCache(context.Background())
+	for i, step := range steps {
+		got := titles(w.Check(ctx, step.status))
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("第 %d 步通知 = %q, 期望 %q", i+1, got, step.want)
+		}
+	}
+}
+
+func TestMonitorPollsFakeAPI(t *testing.T) {
+	srv := teslamatetest.NewServer()
+	defer srv.Close()
+	st := newTestStore(t)
+
+	var sent []Notification
+	m := NewMonitor(srv.Client(), 0, func(n Notification) { sent = append(sent, n) })
+	m.AddCar(1, newChargingWatcher(1, st))
+
+	// 第一次轮询（fixture 中正在充电）只记录基线
+	m.poll(context.Background())
+	if len(sent) != 0 {
+		t.Fatalf("首次轮询推送了 %q", titles(sent))
+	}
+
+	// 充电完成
+	done, err := json.Marshal(testStatus(t, chargeState("Complete", 90)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Handle("/api/v1/cars/1/status", http.StatusOK, string(done))
+	m.poll(context.Background())
+	if want := []string{"charging: ✅ 充电完成"}; !reflect.DeepEqual(titles(sent), want) {
+		t.Errorf("通知 = %q, 期望 %q", titles(sent), want)
+	}
+
+	// 获取状态失败时跳过本次轮询
+	srv.Handle("/api/v1/cars/1/status", http.StatusBadGateway, "")
+	m.poll(context.Background())
+	if len(sent) != 1 {
+		t.Errorf("请求失败时推送了 %q", titles(sent[1:]))
+	}
+}
+
+func TestChargingWatcher(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []checkStep
+	}{
+		{"首次运行只记录基线", []checkStep{
+			{status: testStatus(t, charging(60, 5))},
+			{status: testStatus(t, charging(65, 8))},
+		}},
+		{"开始与完成", []checkStep{
+			{status: testStatus(t, unplugged)},
+			{status: testStatus(t, charging(50, 0)), want: []string{"charging: 🔌 开始充电"}},
+			{status: testStatus(t, charging(70, 12))},
+			{status: testStatus(t, chargeState("Complete", 90)), want: []string{"charging: ✅ 充电完成"}},
+			{status: testStatus(t, unplugged)},
+		}},
+		{"中途停止", []checkStep{
+			{status: testStatus(t, unplugged)},
+			{status: testStatus(t, charging(50, 0)), want: []string{"charging: 🔌 开始充电"}},
+			{status: testStatus(t, chargeState("Stopped", 55)), want: []string{"charging: ⏹️ 充电已停止"}},
+		}},
+		{"拔枪结束", []checkStep{
+			{status: testStatus(t, unplugged)},
+			{status: testStatus(t, charging(50, 0)), want: []string{"charging: 🔌 开始充电"}},
+			{status: testStatus(t, unplugged), want: []string{"charging: ⏹️ 充电已停止"}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runSteps(t, newChargingWatcher(1, newTestStore(t)), tt.steps)
+		})
+	}
+}
+
+func TestChargingWatcherRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "teslamate-bot.db")
+	st := openTestStore(t, path)
+
+	runSteps(t, newChargingWatcher(1, st), []checkStep{
+		{status: testStatus(t, unplugged)},
+		{status: testStatus(t, charging(50, 0)), want: []string{"charging: 🔌 开始充电"}},
+	})
+
+	// 重启后继续充电不再重复推送开始通知，结束时使用重启前记录的起始电量
+	st.Close()
+	w := newChargingWatcher(1, openTestStore(t, path))
+	runSteps(t, w, []checkStep{
+		{status: testStatus(t, charging(60, 6))},
+	})
+	finished := w.Check(context.Background(), testStatus(t, chargeState("Complete", 80)))
+	if len(finished) != 1 || !strings.Contains(finished[0].Text, "50% → 80%") {
+		t.Errorf("充电完成通知 = %q", finished)
+	}
+}
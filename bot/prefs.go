@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"log"
+	"sync"
+
+	"teslamate-bot/store"
+)
+
+// notifyTopic 推送主题
+type notifyTopic struct {
+	Key     string
+	Name    string
+	Default bool
+}
+
+// notifyTopics 可在 /notify 中开关的推送主题
+var notifyTopics = []notifyTopic{
+	{Key: "charging", Name: "🔌 充电通知", Default: true},
+	{Key: "drive", Name: "🚗 行程总结", Default: true},
+	{Key: "security", Name: "🚨 安全提醒", Default: true},
+	{Key: "tires", Name: "🛞 胎压告警", Default: true},
+	{Key: "update", Name: "📲 软件更新", Default: true},
+	{Key: "drain", Name: "🌅 早间掉电报告", Default: true},
+	{Key: "digest", Name: "📊 定时摘要", Default: true},
+	{Key: "state", Name: "🔄 状态变化", Default: false},
+	{Key: "offline", Name: "⚫ 离线告警", Default: true},
+	{Key: "api", Name: "🛰️ API 状态", Default: true},
+}
+
+// findTopic 根据键查找推送主题
+func findTopic(key string) (notifyTopic, bool) {
+	for _, t := range notifyTopics {
+		if t.Key == key {
+			return t, true
+		}
+	}
+	return notifyTopic{}, false
+}
+
+// Preferences 每个会话的推送偏好与当前车辆
+type Preferences struct {
+	mu    sync.Mutex
+	store *store.Store
+	chats map[int64]store.ChatPrefs
+}
+
+// NewPreferences 从状态存储加载会话偏好
+func NewPreferences(st *store.Store) *Preferences {
+	chats, err := st.AllChatPrefs()
+	if err != nil {
+		log.Printf("加载会话偏好失败: %v", err)
+		chats = make(map[int64]store.ChatPrefs)
+	}
+	return &Preferences{store: st, chats: chats}
+}
+
+// Enabled 判断会话是否订阅了某个主题（未设置时使用主题默认值）
+func (p *Preferences) Enabled(chatID int64, topic string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if v, ok := p.chats[chatID].Notify[topic]; ok {
+		return v
+	}
+	t, _ := findTopic(topic)
+	return t.Default
+}
+
+// Toggle 切换会话对某个主题的订阅，返回切换后的状态
+func (p *Preferences) Toggle(chatID int64, topic string) bool {
+	enabled := !p.Enabled(chatID, topic)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	prefs := p.chats[chatID]
+	if prefs.Notify == nil {
+		prefs.Notify = make(map[string]bool)
+	}
+	prefs.Notify[topic] = enabled
+	p.save(chatID, prefs)
+	return enabled
+}
+
+// ActiveCar 获取会话通过 /cars 选择的车辆
+func (p *Preferences) ActiveCar(chatID int64) (int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	carID := p.chats[chatID].ActiveCar
+	return carID, carID != 0
+}
+
+// SetActiveCar 设置会话的当前车辆
+func (p *Preferences) SetActiveCar(chatID int64, carID int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	prefs := p.chats[chatID]
+	prefs.ActiveCar = carID
+	p.save(chatID, prefs)
+}
+
+// save 更新并保存会话偏好（调用方需持有锁）
+func (p *Preferences) save(chatID int64, prefs store.ChatPrefs) {
+	p.chats[chatID] = prefs
+	if err := p.store.PutChatPrefs(chatID, prefs); err != nil {
+		log.Printf("保存会话偏好失败: %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"teslamate-bot/client"
+	"teslamate-bot/config"
+	"teslamate-bot/models"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Scheduler 定时任务调度器，按 cron 表达式发送摘要消息
+type Scheduler struct {
+	cron *cron.Cron
+	jobs []*digestJob
+}
+
+// NewScheduler 根据配置创建调度器（时区为空时使用本地时区）
+func NewScheduler(cfg config.SchedulerConfig, tmClient client.TeslaMateAPI, cars []models.Car, notify func(n Notification)) (*Scheduler, error) {
+	loc, err := cfg.Location()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Scheduler{cron: cron.New(cron.WithLocation(loc))}
+	for _, digest := range cfg.Digests {
+		job := &digestJob{cfg: digest, client: tmClient, cars: cars, notify: notify}
+		if _, err := s.cron.AddJob(digest.Schedule, job); err != nil {
+			return nil, fmt.Errorf("摘要 %s 的 schedule 无效: %w", digest.Name, err)
+		}
+		s.jobs = append(s.jobs, job)
+		log.Printf("已添加定时摘要: %s (%s, %s)", digest.Name, digest.Schedule, loc)
+	}
+
+	return s, nil
+}
+
+// Start 启动调度器（非阻塞），ctx 用于任务中的请求
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		job.ctx = ctx
+	}
+	s.cron.Start()
+}
+
+// Stop 停止调度器，返回的通道在正在执行的任务结束后关闭
+func (s *Scheduler) Stop() <-chan struct{} {
+	return s.cron.Stop().Done()
+}
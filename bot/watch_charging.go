@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"teslamate-bot/models"
+	"teslamate-bot/store"
+)
+
+// chargingState 充电监控的持久化状态
+type chargingState struct {
+	Initialized bool    `json:"initialized"`
+	Charging    bool    `json:"charging"`
+	StartedAt   string  `json:"started_at"`
+	StartLevel  int     `json:"start_level"`
+	EnergyAdded float64 `json:"energy_added"`
+}
+
+// chargingWatcher 监控充电开始与结束
+type chargingWatcher struct {
+	key   string
+	store *store.Store
+	state chargingState
+}
+
+// newChargingWatcher 创建充电监控项并恢复上次保存的状态
+func newChargingWatcher(carID int, st *store.Store) *chargingWatcher {
+	w := &chargingWatcher{key: carKey("charging", carID), store: st}
+	if _, err := st.Get(w.Name(), &w.state); err != nil {
+		log.Printf("恢复充电监控状态失败: %v", err)
+	}
+	return w
+}
+
+// Name 监控项名称
+func (w *chargingWatcher) Name() string {
+	return w.key
+}
+
+// Check 根据充电状态的变化生成开始/结束通知
+func (w *chargingWatcher) Check(ctx context.Context, statusResp *models.StatusResponse) []Notification {
+	status := statusResp.Data.Status
+	charging := status.ChargingDetails.PluggedIn && status.ChargingDetails.ChargingState == "Charging"
+
+	var messages []Notification
+	prev := w.state
+
+	switch {
+	case !prev.Initialized:
+		// 首次运行只记录基线，不推送（避免重启后重复通知）
+		w.state = chargingState{Initialized: true, Charging: charging}
+		if charging {
+			w.state.StartedAt = time.Now().Format(time.RFC3339)
+			w.state.StartLevel = status.BatteryDetails.BatteryLevel
+			w.state.EnergyAdded = status.ChargingDetails.ChargeEnergyAdded
+		}
+
+	case charging && !prev.Charging:
+		w.state.Charging = true
+		w.state.StartedAt = time.Now().Format(time.RFC3339)
+		w.state.StartLevel = status.BatteryDetails.BatteryLevel
+		w.state.EnergyAdded = status.ChargingDetails.ChargeEnergyAdded
+		messages = append(messages, Notification{Topic: "charging", Text: formatChargingStarted(&status)})
+
+	case charging:
+		w.state.EnergyAdded = status.ChargingDetails.ChargeEnergyAdded
+
+	case prev.Charging:
+		// 拔枪后 charge_energy_added 可能被清零，此时使用最后一次记录的值
+		energy := status.ChargingDetails.ChargeEnergyAdded
+		if energy <= 0 {
+			energy = prev.EnergyAdded
+		}
+		messages = append(messages, Notification{
+			Topic: "charging",
+			Text:  formatChargingFinished(&status, &prev, energy),
+		})
+		w.state = chargingState{Initialized: true}
+	}
+
+	if w.state != prev {
+		if err := w.store.Put(w.Name(), w.state); err != nil {
+			log.Printf("保存充电监控状态失败: %v", err)
+		}
+	}
+
+	return messages
+}
+
+// formatChargingStarted 格式化充电开始通知
+func formatChargingStarted(status *models.CarStatus) string {
+	details := status.ChargingDetails
+	return fmt.Sprintf(
+		"🔌 开始充电\n"+
+			"━━━━━━━━━━━━━━━━━━━━\n"+
+			"🚗 %s\n"+
+			"⚡ 充电功率: %d kW\n"+
+			"🔋 当前电量: %d%%\n"+
+			"🎯 充电上限: %d%%\n"+
+			"⏱️ 预计充满: %s",
+		status.DisplayName,
+		details.ChargerPower,
+		status.BatteryDetails.BatteryLevel,
+		details.ChargeLimitSOC,
+		formatHours(details.TimeToFullCharge),
+	)
+}
+
+// formatChargingFinished 格式化充电结束通知
+func formatChargingFinished(status *models.CarStatus, prev *chargingState, energy float64) string {
+	title := "✅ 充电完成"
+	if status.ChargingDetails.ChargingState != "Complete" {
+		title = "⏹️ 充电已停止"
+	}
+
+	duration := "未知"
+	if startedAt, err := time.Parse(time.RFC3339, prev.StartedAt); err == nil {
+		duration = formatDuration(time.Since(startedAt))
+	}
+
+	return fmt.Sprintf(
+		"%s\n"+
+			"━━━━━━━━━━━━━━━━━━━━\n"+
+			"🚗 %s\n"+
+			"⚡ 充入电量: %.2f kWh\n"+
+			"🔋 电量变化: %d%% → %d%%\n"+
+			"⏱️ 时长: %s",
+		title,
+		status.DisplayName,
+		energy,
+		prev.StartLevel,
+		status.BatteryDetails.BatteryLevel,
+		duration,
+	)
+}
+
+// formatHours 将小时数格式化为"x小时y分"
+func formatHours(hours float64) string {
+	if hours <= 0 {
+		return "未知"
+	}
+	return formatDuration(time.Duration(hours * float64(time.Hour)))
+}
+
+// formatDuration 将时长格式化为"x小时y分"
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	h := int(d.Hours())
+	m := int(d.Minutes()) % 60
+	if h > 0 {
+		return fmt.Sprintf("%d小时%d分", h, m)
+	}
+	return fmt.Sprintf("%d分", m)
+}
@@ -0,0 +1,146 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"teslamate-bot/client"
+	"teslamate-bot/models"
+	"teslamate-bot/store"
+)
+
+const (
+	// driveFallbackInterval 未检测到挡位变化时，定期检查新行程的间隔
+	driveFallbackInterval = 10 * time.Minute
+	// drivePendingTimeout 挂回P挡后等待 TeslaMate 写入行程记录的最长时间
+	drivePendingTimeout = 15 * time.Minute
+)
+
+// driveState 行程监控的持久化状态（最后推送的行程ID保存在事件标记中）
+type driveState struct {
+	Driving      bool   `json:"driving"`
+	PendingSince string `json:"pending_since,omitempty"`
+}
+
+// driveWatcher 监控行程结束并推送行程总结
+type driveWatcher struct {
+	key       string
+	carID     int
+	client    client.TeslaMateAPI
+	store     *store.Store
+	state     driveState
+	last      store.Marker // 最后推送（或首次运行时作为基线）的行程
+	hasLast   bool         // 是否已记录基线
+	lastFetch time.Time
+}
+
+// newDriveWatcher 创建行程监控项并恢复上次保存的状态
+func newDriveWatcher(carID int, tmClient client.TeslaMateAPI, st *store.Store) *driveWatcher {
+	w := &driveWatcher{key: carKey("drive", carID), carID: carID, client: tmClient, store: st}
+	if _, err := st.Get(w.Name(), &w.state); err != nil {
+		log.Printf("恢复行程监控状态失败: %v", err)
+	}
+	var err error
+	if w.last, w.hasLast, err = st.Marker(w.Name()); err != nil {
+		log.Printf("恢复最后行程失败: %v", err)
+	}
+	return w
+}
+
+// Name 监控项名称
+func (w *driveWatcher) Name() string {
+	return w.key
+}
+
+// Check 挡位回到P（或为空）后检查是否出现新的行程记录
+func (w *driveWatcher) Check(ctx context.Context, statusResp *models.StatusResponse) []Notification {
+	prev := w.state
+	now := time.Now()
+
+	switch statusResp.Data.Status.DrivingDetails.ShiftState {
+	case "D", "R", "N":
+		w.state.Driving = true
+		w.state.PendingSince = ""
+	default:
+		if w.state.Driving {
+			w.state.Driving = false
+			w.state.PendingSince = now.Format(time.RFC3339)
+		}
+	}
+
+	var messages []Notification
+	if w.shouldFetch(now) {
+		messages = w.fetchLatest(ctx, now)
+	}
+
+	if w.state != prev {
+		if err := w.store.Put(w.Name(), w.state); err != nil {
+			log.Printf("保存行程监控状态失败: %v", err)
+		}
+	}
+
+	return messages
+}
+
+// shouldFetch 判断本次轮询是否需要请求行程列表
+func (w *driveWatcher) shouldFetch(now time.Time) bool {
+	if !w.hasLast || w.state.PendingSince != "" {
+		return true
+	}
+	return !w.state.Driving && now.Sub(w.lastFetch) >= driveFallbackInterval
+}
+
+// fetchLatest 获取最新行程，发现新的已结束行程时生成通知
+func (w *driveWatcher) fetchLatest(ctx context.Context, now time.Time) []Notification {
+	w.lastFetch = now
+
+	drive, units, err := w.client.GetLatestDrive(ctx, w.carID)
+	if err != nil {
+		log.Printf("行程监控获取最新行程失败: %v", err)
+		var apiErr *client.Error
+		if !w.hasLast && errors.As(err, &apiErr) && apiErr.Kind == client.KindNotFound {
+			// 近期没有行程时也完成初始化，之后出现的任何行程都视为新行程；
+			// 其他错误不能作为基线，否则恢复后会把旧行程当作新行程推送
+			w.setLast(0, now)
+		}
+		w.expirePending(now)
+		return nil
+	}
+
+	if !w.hasLast {
+		// 首次运行只记录基线，不推送
+		w.setLast(drive.DriveID, now)
+		return nil
+	}
+
+	if int64(drive.DriveID) <= w.last.ID || drive.EndDate == "" {
+		w.expirePending(now)
+		return nil
+	}
+
+	w.setLast(drive.DriveID, now)
+	w.state.PendingSince = ""
+	return []Notification{{Topic: "drive", Text: formatDrive("🏁 行程结束", drive, units)}}
+}
+
+// setLast 记录最后推送的行程
+func (w *driveWatcher) setLast(driveID int, now time.Time) {
+	w.last = store.Marker{ID: int64(driveID), At: now}
+	w.hasLast = true
+	if err := w.store.SetMarker(w.Name(), w.last); err != nil {
+		log.Printf("保存最后行程失败: %v", err)
+	}
+}
+
+// expirePending 等待超时后放弃本次行程检查，交由定期检查兜底
+func (w *driveWatcher) expirePending(now time.Time) {
+	if w.state.PendingSince == "" {
+		return
+	}
+	since, err := time.Parse(time.RFC3339, w.state.PendingSince)
+	if err != nil || now.Sub(since) >= drivePendingTimeout {
+		w.state.PendingSince = ""
+	}
+}
@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"teslamate-bot/client/teslamatetest"
+	"teslamate-bot/models"
+)
+
+// shift 挡位
+func shift(state string) func(s *models.CarStatus) {
+	return func(s *models.CarStatus) {
+		s.DrivingDetails.ShiftState = state
+	}
+}
+
+// nextDrive 将 fixture 中的行程替换为下一次行程
+func nextDrive(srv *teslamatetest.Server, carID string) {
+	body := strings.Replace(string(teslamatetest.Fixture("drives.json")), `"drive_id": 1456`, `"drive_id": 1457`, 1)
+	srv.Handle("/api/v1/cars/"+carID+"/drives", http.StatusOK, body)
+}
+
+func TestDriveWatcher(t *testing.T) {
+	srv := teslamatetest.NewServer()
+	defer srv.Close()
+
+	w := newDriveWatcher(1, srv.Client(), newTestStore(t))
+	runSteps(t, w, []checkStep{
+		// 首次运行以最近的行程为基线
+		{status: testStatus(t, shift("P"))},
+		{status: testStatus(t, shift("D"))},
+	})
+	nextDrive(srv, "1")
+	runSteps(t, w, []checkStep{
+		{status: testStatus(t, shift("P")), want: []string{"drive: 🏁 行程结束"}},
+		{status: testStatus(t, shift("P"))},
+	})
+}
+
+func TestDriveWatcherBaselineOnError(t *testing.T) {
+	srv := teslamatetest.NewServer()
+	defer srv.Close()
+	srv.Handle("/api/v1/cars/1/drives", http.StatusBadGateway, "")
+
+	w := newDriveWatcher(1, srv.Client(), newTestStore(t))
+	runSteps(t, w, []checkStep{{status: testStatus(t, shift("P"))}})
+	if w.hasLast {
+		t.Fatalf("请求失败时记录了基线 %+v", w.last)
+	}
+
+	// 恢复后以当时最近的行程为基线，不把旧行程当作新行程推送
+	srv.HandleFixture("/api/v1/cars/1/drives", "drives.json")
+	runSteps(t, w, []checkStep{{status: testStatus(t, shift("P"))}})
+	if !w.hasLast || w.last.ID != 1456 {
+		t.Errorf("基线 = %+v, %v, 期望行程 1456", w.last, w.hasLast)
+	}
+}
+
+func TestDriveWatcherNoDrives(t *testing.T) {
+	srv := teslamatetest.NewServer()
+	defer srv.Close()
+
+	// 车辆 2 近期没有行程，以 0 为基线，之后出现的行程都是新行程
+	w := newDriveWatcher(2, srv.Client(), newTestStore(t))
+	runSteps(t, w, []checkStep{
+		{status: testStatus(t, shift("P"))},
+		{status: testStatus(t, shift("D"))},
+	})
+	nextDrive(srv, "2")
+	runSteps(t, w, []checkStep{
+		{status: testStatus(t, shift("P")), want: []string{"drive: 🏁 行程结束"}},
+	})
+}
+
+func TestDriveWatcherRestart(t *testing.T) {
+	srv := teslamatetest.NewServer()
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "teslamate-bot.db")
+	st := openTestStore(t, path)
+
+	w := newDriveWatcher(1, srv.Client(), st)
+	runSteps(t, w, []checkStep{
+		{status: testStatus(t, shift("P"))},
+		{status: testStatus(t, shift("D"))},
+	})
+	nextDrive(srv, "1")
+	runSteps(t, w, []checkStep{
+		{status: testStatus(t, shift("P")), want: []string{"drive: 🏁 行程结束"}},
+	})
+
+	// 重启后不重复推送已推送的行程
+	st.Close()
+	runSteps(t, newDriveWatcher(1, srv.Client(), openTestStore(t, path)), []checkStep{
+		{status: testStatus(t, shift("P"))},
+	})
+}
@@ -0,0 +1,163 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"teslamate-bot/config"
+	"teslamate-bot/models"
+	"teslamate-bot/store"
+)
+
+// securityState 安全监控的持久化状态
+type securityState struct {
+	OpenSince   string `json:"open_since,omitempty"`
+	Alerted     bool   `json:"alerted"`
+	LastAlertAt string `json:"last_alert_at,omitempty"`
+	NextAlert   int    `json:"next_alert"` // 下次提醒间隔（分钟）
+}
+
+// securityWatcher 停车后车辆未锁或门窗未关时发出告警
+type securityWatcher struct {
+	key   string
+	store *store.Store
+	cfg   config.SecurityWatchConfig
+	state securityState
+}
+
+// newSecurityWatcher 创建安全监控项并恢复上次保存的状态
+func newSecurityWatcher(carID int, cfg config.SecurityWatchConfig, st *store.Store) *securityWatcher {
+	w := &securityWatcher{key: carKey("security", carID), store: st, cfg: cfg}
+	if _, err := st.Get(w.Name(), &w.state); err != nil {
+		log.Printf("恢复安全监控状态失败: %v", err)
+	}
+	return w
+}
+
+// Name 监控项名称
+func (w *securityWatcher) Name() string {
+	return w.key
+}
+
+// Check 检查停车状态下的车锁与门窗
+func (w *securityWatcher) Check(ctx context.Context, statusResp *models.StatusResponse) []Notification {
+	status := &statusResp.Data.Status
+	prev := w.state
+	now := time.Now()
+
+	issues := securityIssues(status)
+	var messages []Notification
+
+	switch {
+	case !isParked(status):
+		// 驶离时可能仍未锁车或门窗未关，不能提示已解除，停车后重新计时
+		if prev.Alerted {
+			messages = append(messages, Notification{
+				Topic: "security",
+				Text:  fmt.Sprintf("🚗 车辆已驶离\n━━━━━━━━━━━━━━━━━━━━\n🚗 %s\n行驶中暂停安全提醒，停车后重新检查", status.DisplayName),
+			})
+		}
+		w.state = securityState{}
+
+	case len(issues) == 0:
+		if prev.Alerted {
+			messages = append(messages, Notification{
+				Topic: "security",
+				Text:  fmt.Sprintf("✅ 安全解除\n━━━━━━━━━━━━━━━━━━━━\n🚗 %s\n车辆已锁定，门窗均已关闭", status.DisplayName),
+			})
+		}
+		w.state = securityState{}
+
+	case status.CarStatusInfo.IsUserPresent:
+		// 有人在车内时不计时，已告警的情况下也暂停提醒
+		if !prev.Alerted {
+			w.state.OpenSince = ""
+		}
+
+	case w.state.OpenSince == "":
+		w.state.OpenSince = now.Format(time.RFC3339)
+
+	default:
+		openSince, _ := time.Parse(time.RFC3339, w.state.OpenSince)
+		lastAlertAt, _ := time.Parse(time.RFC3339, w.state.LastAlertAt)
+		grace := time.Duration(w.cfg.GraceMinutes) * time.Minute
+
+		alert := false
+		switch {
+		case !w.state.Alerted && now.Sub(openSince) >= grace:
+			// 超过宽限期，首次告警
+			alert = true
+			w.state.Alerted = true
+			w.state.NextAlert = w.cfg.RealertMinutes
+		case w.state.Alerted && now.Sub(lastAlertAt) >= time.Duration(w.state.NextAlert)*time.Minute:
+			// 再次提醒，间隔逐次翻倍直至上限
+			alert = true
+			w.state.NextAlert = min(w.state.NextAlert*2, w.cfg.MaxRealertMinutes)
+		}
+
+		if alert {
+			w.state.LastAlertAt = now.Format(time.RFC3339)
+			messages = append(messages, Notification{
+				Topic: "security",
+				Text:  formatSecurityAlert(status, issues, now.Sub(openSince)),
+			})
+		}
+	}
+
+	if w.state != prev {
+		if err := w.store.Put(w.Name(), w.state); err != nil {
+			log.Printf("保存安全监控状态失败: %v", err)
+		}
+	}
+
+	return messages
+}
+
+// isParked 判断车辆是否处于停车状态
+func isParked(status *models.CarStatus) bool {
+	switch status.DrivingDetails.ShiftState {
+	case "D", "R", "N":
+		return false
+	}
+	return status.State != "driving"
+}
+
+// securityIssues 列出当前未锁/未关闭的项目
+func securityIssues(status *models.CarStatus) []string {
+	info := status.CarStatusInfo
+
+	var issues []string
+	if !info.Locked {
+		issues = append(issues, "🔓 车辆未锁定")
+	}
+	if info.DoorsOpen {
+		issues = append(issues, "🚪 车门未关")
+	}
+	if info.TrunkOpen {
+		issues = append(issues, "🧳 后备箱未关")
+	}
+	if info.FrunkOpen {
+		issues = append(issues, "📦 前备箱未关")
+	}
+	if info.WindowsOpen {
+		issues = append(issues, "🪟 车窗未关")
+	}
+	return issues
+}
+
+// formatSecurityAlert 格式化安全告警
+func formatSecurityAlert(status *models.CarStatus, issues []string, openFor time.Duration) string {
+	return fmt.Sprintf(
+		"🚨 安全提醒\n"+
+			"━━━━━━━━━━━━━━━━━━━━\n"+
+			"🚗 %s\n"+
+			"%s\n"+
+			"⏱️ 已持续: %s",
+		status.DisplayName,
+		strings.Join(issues, "\n"),
+		formatDuration(openFor),
+	)
+}
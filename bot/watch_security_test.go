@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"teslamate-bot/config"
+	"teslamate-bot/models"
+)
+
+// unlocked 停车后未锁车
+func unlocked(s *models.CarStatus) {
+	s.CarStatusInfo.Locked = false
+}
+
+// windowOpen 车窗未关
+func windowOpen(s *models.CarStatus) {
+	s.CarStatusInfo.WindowsOpen = true
+}
+
+// userPresent 有人在车内
+func userPresent(s *models.CarStatus) {
+	s.CarStatusInfo.IsUserPresent = true
+}
+
+// ago 距今 d 的 RFC3339 时间，用于模拟时间流逝
+func ago(d time.Duration) string {
+	return time.Now().Add(-d).Format(time.RFC3339)
+}
+
+// newTestSecurityWatcher 宽限期 5 分钟，再提醒间隔从 30 分钟翻倍至 100 分钟
+func newTestSecurityWatcher(t *testing.T) *securityWatcher {
+	cfg := config.SecurityWatchConfig{GraceMinutes: 5, RealertMinutes: 30, MaxRealertMinutes: 100}
+	return newSecurityWatcher(1, cfg, newTestStore(t))
+}
+
+func TestSecurityWatcherRealert(t *testing.T) {
+	w := newTestSecurityWatcher(t)
+	open := testStatus(t, shift("P"), unlocked, windowOpen)
+
+	// 宽限期内不告警
+	runSteps(t, w, []checkStep{{status: open}, {status: open}})
+
+	w.state.OpenSince = ago(6 * time.Minute)
+	runSteps(t, w, []checkStep{
+		{status: open, want: []string{"security: 🚨 安全提醒"}},
+		{status: open},
+	})
+
+	// 再提醒间隔逐次翻倍直至上限：30 → 60 → 100 → 100
+	for _, tt := range []struct {
+		elapsed time.Duration
+		next    int
+	}{
+		{29 * time.Minute, 30},
+		{31 * time.Minute, 60},
+		{61 * time.Minute, 100},
+		{101 * time.Minute, 100},
+	} {
+		w.state.LastAlertAt = ago(tt.elapsed)
+		var want []string
+		if tt.elapsed > time.Duration(w.state.NextAlert)*time.Minute {
+			want = []string{"security: 🚨 安全提醒"}
+		}
+		runSteps(t, w, []checkStep{{status: open, want: want}})
+		if w.state.NextAlert != tt.next {
+			t.Errorf("距上次提醒 %s 后下次间隔 = %d 分钟, 期望 %d", tt.elapsed, w.state.NextAlert, tt.next)
+		}
+	}
+
+	// 锁车并关窗后解除
+	runSteps(t, w, []checkStep{
+		{status: testStatus(t, shift("P")), want: []string{"security: ✅ 安全解除"}},
+		{status: testStatus(t, shift("P"))},
+	})
+}
+
+func TestSecurityWatcherDriveAway(t *testing.T) {
+	w := newTestSecurityWatcher(t)
+	open := testStatus(t, shift("P"), unlocked)
+	runSteps(t, w, []checkStep{{status: open}})
+	w.state.OpenSince = ago(6 * time.Minute)
+	runSteps(t, w, []checkStep{{status: open, want: []string{"security: 🚨 安全提醒"}}})
+
+	// 未锁车直接驶离时不能提示已锁定
+	runSteps(t, w, []checkStep{
+		{status: testStatus(t, shift("D"), unlocked), want: []string{"security: 🚗 车辆已驶离"}},
+		{status: testStatus(t, shift("D"), unlocked)},
+		// 停车后重新计时
+		{status: open},
+	})
+	if w.state.Alerted || w.state.OpenSince == "" {
+		t.Errorf("停车后状态 = %+v, 期望重新计时", w.state)
+	}
+}
+
+func TestSecurityWatcherUserPresent(t *testing.T) {
+	w := newTestSecurityWatcher(t)
+	present := testStatus(t, shift("P"), unlocked, userPresent)
+
+	runSteps(t, w, []checkStep{{status: present}, {status: present}})
+	if w.state.OpenSince != "" {
+		t.Errorf("有人在车内时开始计时: %+v", w.state)
+	}
+
+	// 未告警时驶离不推送
+	runSteps(t, w, []checkStep{
+		{status: testStatus(t, shift("P"), unlocked)},
+		{status: testStatus(t, shift("D"), unlocked)},
+	})
+}
@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"teslamate-bot/config"
+	"teslamate-bot/models"
+	"teslamate-bot/store"
+)
+
+// snapshotPruneInterval 清理过期快照的间隔
+const snapshotPruneInterval = 24 * time.Hour
+
+// carSnapshot 车辆状态快照，用于保留历史记录
+type carSnapshot struct {
+	State        string  `json:"state"`
+	BatteryLevel int     `json:"battery_level"`
+	RatedRange   float64 `json:"rated_range"`
+	Odometer     float64 `json:"odometer"`
+	Unit         string  `json:"unit"` // 续航与里程的单位
+	PluggedIn    bool    `json:"plugged_in"`
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+	InsideTemp   float64 `json:"inside_temp"`
+	OutsideTemp  float64 `json:"outside_temp"`
+	Version      string  `json:"version"`
+}
+
+// snapshotWatcher 按固定间隔保存车辆状态快照，并定期清理过期快照（不推送消息）
+type snapshotWatcher struct {
+	key       string
+	carID     int
+	store     *store.Store
+	interval  time.Duration
+	retention time.Duration
+	lastSaved time.Time
+	lastPrune time.Time
+}
+
+// newSnapshotWatcher 创建快照监控项，从最新快照的时间继续计算间隔
+func newSnapshotWatcher(carID int, cfg config.StorageConfig, st *store.Store) *snapshotWatcher {
+	w := &snapshotWatcher{
+		key:       carKey("snapshot", carID),
+		carID:     carID,
+		store:     st,
+		interval:  time.Duration(cfg.SnapshotMinutes) * time.Minute,
+		retention: time.Duration(cfg.SnapshotRetentionDays) * 24 * time.Hour,
+	}
+	var last carSnapshot
+	at, _, err := st.LatestSnapshot(carID, &last)
+	if err != nil {
+		log.Printf("读取最新快照失败: %v", err)
+	}
+	w.lastSaved = at
+	return w
+}
+
+// Name 监控项名称
+func (w *snapshotWatcher) Name() string {
+	return w.key
+}
+
+// Check 距上次快照超过间隔时保存快照
+func (w *snapshotWatcher) Check(ctx context.Context, statusResp *models.StatusResponse) []Notification {
+	now := time.Now()
+	if now.Sub(w.lastSaved) < w.interval {
+		return nil
+	}
+
+	status := &statusResp.Data.Status
+	lat, lon := carPosition(status)
+	snapshot := carSnapshot{
+		State:        status.State,
+		BatteryLevel: status.BatteryDetails.BatteryLevel,
+		RatedRange:   status.BatteryDetails.RatedBatteryRange,
+		Odometer:     status.Odometer,
+		Unit:         statusResp.Data.Units.UnitOfLength,
+		PluggedIn:    status.ChargingDetails.PluggedIn,
+		Latitude:     lat,
+		Longitude:    lon,
+		InsideTemp:   status.ClimateDetails.InsideTemp,
+		OutsideTemp:  status.ClimateDetails.OutsideTemp,
+		Version:      status.CarVersions.Version,
+	}
+	if err := w.store.PutSnapshot(w.carID, now, snapshot); err != nil {
+		log.Printf("保存车辆状态快照失败: %v", err)
+		return nil
+	}
+	w.lastSaved = now
+
+	if now.Sub(w.lastPrune) >= snapshotPruneInterval {
+		w.lastPrune = now
+		pruned, err := w.store.PruneSnapshots(now.Add(-w.retention))
+		if err != nil {
+			log.Printf("清理过期快照失败: %v", err)
+		} else if pruned > 0 {
+			log.Printf("已清理 %d 条过期车辆快照", pruned)
+		}
+	}
+	return nil
+}
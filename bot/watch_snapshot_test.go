@@ -0,0 +1,27 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"teslamate-bot/config"
+	"teslamate-bot/models"
+)
+
+func TestSnapshotPosition(t *testing.T) {
+	st := newTestStore(t)
+	w := newSnapshotWatcher(1, config.StorageConfig{SnapshotMinutes: 15, SnapshotRetentionDays: 90}, st)
+
+	// 与地理围栏一致：优先使用顶层坐标，嵌套的 location 只作为回退
+	w.Check(context.Background(), testStatus(t, func(s *models.CarStatus) {
+		s.CarGeodata = models.CarGeodata{Latitude: 31.2, Longitude: 121.5}
+	}))
+
+	var got carSnapshot
+	if _, found, err := st.LatestSnapshot(1, &got); err != nil || !found {
+		t.Fatalf("未保存快照: %v, %v", found, err)
+	}
+	if got.Latitude != 31.2 || got.Longitude != 121.5 {
+		t.Errorf("快照位置 = %v, %v, 期望 31.2, 121.5", got.Latitude, got.Longitude)
+	}
+}
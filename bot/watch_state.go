@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"teslamate-bot/config"
+	"teslamate-bot/models"
+	"teslamate-bot/store"
+)
+
+// carStateLabels 车辆状态的显示名称
+var carStateLabels = map[string]string{
+	"online":    "🟢 在线",
+	"asleep":    "😴 休眠",
+	"suspended": "💤 准备休眠",
+	"offline":   "⚫ 离线",
+	"driving":   "🚗 驾驶中",
+	"charging":  "🔌 充电中",
+	"updating":  "📲 更新中",
+}
+
+// carStateLabel 返回车辆状态的显示名称
+func carStateLabel(state string) string {
+	if label, ok := carStateLabels[state]; ok {
+		return label
+	}
+	return "❔ " + state
+}
+
+// carStateRecord 车辆状态监控的持久化状态
+type carStateRecord struct {
+	State          string `json:"state"`
+	Since          string `json:"since"`
+	OfflineAlerted bool   `json:"offline_alerted"`
+}
+
+// stateWatcher 监控车辆状态变化及长时间离线
+type stateWatcher struct {
+	key   string
+	store *store.Store
+	cfg   config.StateWatchConfig
+	state carStateRecord
+}
+
+// newStateWatcher 创建车辆状态监控项并恢复上次保存的状态
+func newStateWatcher(carID int, cfg config.StateWatchConfig, st *store.Store) *stateWatcher {
+	w := &stateWatcher{key: carKey("state", carID), store: st, cfg: cfg}
+	if _, err := st.Get(w.Name(), &w.state); err != nil {
+		log.Printf("恢复车辆状态监控失败: %v", err)
+	}
+	return w
+}
+
+// Name 监控项名称
+func (w *stateWatcher) Name() string {
+	return w.key
+}
+
+// Check 状态变化时推送（附带上一状态持续时长），离线超过阈值时告警
+func (w *stateWatcher) Check(ctx context.Context, statusResp *models.StatusResponse) []Notification {
+	status := &statusResp.Data.Status
+	if status.State == "" {
+		return nil
+	}
+
+	now := time.Now()
+	since := now
+	if t, err := time.Parse(time.RFC3339, status.StateSince); err == nil {
+		since = t
+	}
+
+	prev := w.state
+	var messages []Notification
+
+	switch {
+	case prev.State == "":
+		// 首次运行只记录基线，不推送
+		w.state = carStateRecord{State: status.State, Since: since.Format(time.RFC3339)}
+
+	case status.State != prev.State:
+		prevSince, _ := time.Parse(time.RFC3339, prev.Since)
+		lasted := formatDuration(since.Sub(prevSince))
+
+		messages = append(messages, Notification{
+			Topic: "state",
+			Text: fmt.Sprintf("%s\n━━━━━━━━━━━━━━━━━━━━\n🚗 %s\n⏮️ 此前%s %s",
+				carStateLabel(status.State), status.DisplayName, carStateLabel(prev.State), lasted),
+		})
+		if prev.OfflineAlerted {
+			messages = append(messages, Notification{
+				Topic: "offline",
+				Text:  fmt.Sprintf("✅ 车辆已恢复连接\n━━━━━━━━━━━━━━━━━━━━\n🚗 %s\n⚫ 离线时长: %s", status.DisplayName, lasted),
+			})
+		}
+		w.state = carStateRecord{State: status.State, Since: since.Format(time.RFC3339)}
+
+	case status.State == "offline" && !prev.OfflineAlerted:
+		prevSince, _ := time.Parse(time.RFC3339, prev.Since)
+		if offline := now.Sub(prevSince); offline >= time.Duration(w.cfg.OfflineAlertMinutes)*time.Minute {
+			w.state.OfflineAlerted = true
+			messages = append(messages, Notification{
+				Topic: "offline",
+				Text: fmt.Sprintf("⚠️ 车辆长时间离线\n━━━━━━━━━━━━━━━━━━━━\n🚗 %s\n⚫ 已离线 %s（非休眠）\n车辆可能处于无网络信号的位置（如地下车库）",
+					status.DisplayName, formatDuration(offline)),
+			})
+		}
+	}
+
+	if w.state != prev {
+		if err := w.store.Put(w.Name(), w.state); err != nil {
+			log.Printf("保存车辆状态监控失败: %v", err)
+		}
+	}
+
+	return messages
+}
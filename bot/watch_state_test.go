@@ -0,0 +1,62 @@
+package bot
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"teslamate-bot/config"
+	"teslamate-bot/models"
+)
+
+// stateSince 车辆状态及其开始时间
+func stateSince(state, since string) func(s *models.CarStatus) {
+	return func(s *models.CarStatus) {
+		s.State = state
+		s.StateSince = since
+	}
+}
+
+func TestStateWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "teslamate-bot.db")
+	st := openTestStore(t, path)
+	cfg := config.StateWatchConfig{OfflineAlertMinutes: 30}
+
+	w := newStateWatcher(1, cfg, st)
+	runSteps(t, w, []checkStep{
+		// 首次运行只记录基线
+		{status: testStatus(t, stateSince("online", ago(2*time.Hour)))},
+		{status: testStatus(t, stateSince("online", ago(2*time.Hour)))},
+		// 没有状态数据时忽略
+		{status: testStatus(t, stateSince("", ""))},
+		{status: testStatus(t, stateSince("asleep", ago(time.Hour))), want: []string{"state: 😴 休眠"}},
+	})
+
+	msgs := w.Check(t.Context(), testStatus(t, stateSince("offline", ago(10*time.Minute))))
+	if len(msgs) != 1 || !strings.Contains(msgs[0].Text, "此前😴 休眠 50分") {
+		t.Fatalf("通知 = %+v, 期望附带上一状态持续时长", msgs)
+	}
+
+	// 离线未超过阈值不告警，超过后只告警一次
+	runSteps(t, w, []checkStep{{status: testStatus(t, stateSince("offline", ago(10*time.Minute)))}})
+	w.state.Since = ago(31 * time.Minute)
+	runSteps(t, w, []checkStep{
+		{status: testStatus(t, stateSince("offline", w.state.Since)), want: []string{"offline: ⚠️ 车辆长时间离线"}},
+		{status: testStatus(t, stateSince("offline", w.state.Since))},
+	})
+
+	// 重启后不重复告警，恢复连接时推送
+	st.Close()
+	w = newStateWatcher(1, cfg, openTestStore(t, path))
+	runSteps(t, w, []checkStep{
+		{status: testStatus(t, stateSince("offline", w.state.Since))},
+		{status: testStatus(t, stateSince("online", ago(0))), want: []string{
+			"state: 🟢 在线",
+			"offline: ✅ 车辆已恢复连接",
+		}},
+	})
+	if w.state.OfflineAlerted {
+		t.Errorf("恢复连接后状态 = %+v", w.state)
+	}
+}
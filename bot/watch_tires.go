@@ -0,0 +1,133 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"teslamate-bot/config"
+	"teslamate-bot/models"
+	"teslamate-bot/store"
+)
+
+// tireReading 单个轮胎的胎压读数
+type tireReading struct {
+	Name     string
+	Pressure float64
+	Warning  bool
+}
+
+// tireReadings 按左前、右前、左后、右后的顺序返回胎压读数
+func tireReadings(tpms models.TPMSDetails) []tireReading {
+	return []tireReading{
+		{Name: "左前", Pressure: tpms.TPMSPressureFL, Warning: tpms.TPMSSoftWarningFL},
+		{Name: "右前", Pressure: tpms.TPMSPressureFR, Warning: tpms.TPMSSoftWarningFR},
+		{Name: "左后", Pressure: tpms.TPMSPressureRL, Warning: tpms.TPMSSoftWarningRL},
+		{Name: "右后", Pressure: tpms.TPMSPressureRR, Warning: tpms.TPMSSoftWarningRR},
+	}
+}
+
+// tiresState 胎压监控的持久化状态
+type tiresState struct {
+	Issues string `json:"issues,omitempty"` // 上次告警时的问题列表
+}
+
+// tiresWatcher 胎压过低、四轮差异过大或出现TPMS警告时告警
+type tiresWatcher struct {
+	key   string
+	store *store.Store
+	cfg   config.TiresWatchConfig
+	state tiresState
+}
+
+// newTiresWatcher 创建胎压监控项并恢复上次保存的状态
+func newTiresWatcher(carID int, cfg config.TiresWatchConfig, st *store.Store) *tiresWatcher {
+	w := &tiresWatcher{key: carKey("tires", carID), store: st, cfg: cfg}
+	if _, err := st.Get(w.Name(), &w.state); err != nil {
+		log.Printf("恢复胎压监控状态失败: %v", err)
+	}
+	return w
+}
+
+// Name 监控项名称
+func (w *tiresWatcher) Name() string {
+	return w.key
+}
+
+// Check 检查胎压，问题出现或变化时告警，恢复正常时通知
+func (w *tiresWatcher) Check(ctx context.Context, statusResp *models.StatusResponse) []Notification {
+	status := &statusResp.Data.Status
+	readings := tireReadings(status.TPMSDetails)
+
+	// 车辆休眠后可能没有胎压数据，此时保持原状态
+	for _, r := range readings {
+		if r.Pressure <= 0 {
+			return nil
+		}
+	}
+
+	unit := statusResp.Data.Units.UnitOfPressure
+	issues := strings.Join(w.tireIssues(readings, unit), "\n")
+	if issues == w.state.Issues {
+		return nil
+	}
+
+	var messages []Notification
+	if issues != "" {
+		messages = append(messages, Notification{
+			Topic: "tires",
+			Text: fmt.Sprintf("🛞 胎压异常\n━━━━━━━━━━━━━━━━━━━━\n🚗 %s\n%s\n━━━━━━━━━━━━━━━━━━━━\n%s",
+				status.DisplayName, issues, formatTires(readings, unit)),
+		})
+	} else {
+		messages = append(messages, Notification{
+			Topic: "tires",
+			Text: fmt.Sprintf("✅ 胎压恢复正常\n━━━━━━━━━━━━━━━━━━━━\n🚗 %s\n%s",
+				status.DisplayName, formatTires(readings, unit)),
+		})
+	}
+
+	w.state.Issues = issues
+	if err := w.store.Put(w.Name(), w.state); err != nil {
+		log.Printf("保存胎压监控状态失败: %v", err)
+	}
+
+	return messages
+}
+
+// tireIssues 列出胎压问题（阈值为0时不检查对应项）
+func (w *tiresWatcher) tireIssues(readings []tireReading, unit string) []string {
+	lowest, highest := readings[0].Pressure, readings[0].Pressure
+	for _, r := range readings[1:] {
+		lowest = min(lowest, r.Pressure)
+		highest = max(highest, r.Pressure)
+	}
+
+	var issues []string
+	for _, r := range readings {
+		if r.Warning {
+			issues = append(issues, fmt.Sprintf("⚠️ %s: TPMS 胎压警告", r.Name))
+		}
+		if w.cfg.MinPressure > 0 && r.Pressure < w.cfg.MinPressure {
+			issues = append(issues, fmt.Sprintf("⚠️ %s: %.2f %s，低于 %.2f %s", r.Name, r.Pressure, unit, w.cfg.MinPressure, unit))
+		}
+	}
+	if w.cfg.MaxDelta > 0 && highest-lowest > w.cfg.MaxDelta {
+		issues = append(issues, fmt.Sprintf("⚠️ 四轮胎压相差 %.2f %s，超过 %.2f %s", highest-lowest, unit, w.cfg.MaxDelta, unit))
+	}
+	return issues
+}
+
+// formatTires 按车辆布局格式化四轮胎压，带TPMS警告的轮胎加⚠️标记
+func formatTires(readings []tireReading, unit string) string {
+	cells := make([]string, len(readings))
+	for i, r := range readings {
+		mark := ""
+		if r.Warning {
+			mark = " ⚠️"
+		}
+		cells[i] = fmt.Sprintf("%s: %.2f %s%s", r.Name, r.Pressure, unit, mark)
+	}
+	return fmt.Sprintf("%s | %s\n%s | %s", cells[0], cells[1], cells[2], cells[3])
+}
@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"testing"
+
+	"teslamate-bot/config"
+	"teslamate-bot/models"
+)
+
+// tires 四轮胎压（左前、右前、左后、右后），清除 TPMS 警告
+func tires(fl, fr, rl, rr float64) func(s *models.CarStatus) {
+	return func(s *models.CarStatus) {
+		s.TPMSDetails = models.TPMSDetails{TPMSPressureFL: fl, TPMSPressureFR: fr, TPMSPressureRL: rl, TPMSPressureRR: rr}
+	}
+}
+
+// tpmsWarningRR 右后轮 TPMS 警告
+func tpmsWarningRR(s *models.CarStatus) {
+	s.TPMSDetails.TPMSSoftWarningRR = true
+}
+
+func TestTiresWatcher(t *testing.T) {
+	cfg := config.TiresWatchConfig{MinPressure: 2.6, MaxDelta: 0.3}
+	w := newTiresWatcher(1, cfg, newTestStore(t))
+
+	runSteps(t, w, []checkStep{
+		{status: testStatus(t, tires(2.9, 2.9, 2.9, 2.9))},
+		{status: testStatus(t, tires(2.9, 2.9, 2.9, 2.5)), want: []string{"tires: 🛞 胎压异常"}},
+		// 问题未变化时不重复告警
+		{status: testStatus(t, tires(2.9, 2.9, 2.9, 2.5))},
+		// 出现新的问题（TPMS 警告）时再次告警
+		{status: testStatus(t, tires(2.9, 2.9, 2.9, 2.5), tpmsWarningRR), want: []string{"tires: 🛞 胎压异常"}},
+		// 休眠时没有胎压数据，保持原状态
+		{status: testStatus(t, tires(0, 0, 0, 0))},
+		{status: testStatus(t, tires(2.9, 2.9, 2.9, 2.5), tpmsWarningRR)},
+		{status: testStatus(t, tires(2.9, 2.85, 2.9, 2.9)), want: []string{"tires: ✅ 胎压恢复正常"}},
+		{status: testStatus(t, tires(2.9, 2.85, 2.9, 2.9))},
+	})
+}
+
+func TestTiresWatcherIssues(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    config.TiresWatchConfig
+		status *models.StatusResponse
+		want   []string
+	}{
+		{"正常", config.TiresWatchConfig{MinPressure: 2.6, MaxDelta: 0.3}, testStatus(t, tires(2.9, 2.8, 2.9, 2.7)), nil},
+		{"低于下限", config.TiresWatchConfig{MinPressure: 2.6}, testStatus(t, tires(2.9, 2.9, 2.9, 2.5)),
+			[]string{"⚠️ 右后: 2.50 bar，低于 2.60 bar"}},
+		{"四轮差异", config.TiresWatchConfig{MaxDelta: 0.3}, testStatus(t, tires(3.0, 2.9, 2.9, 2.6)),
+			[]string{"⚠️ 四轮胎压相差 0.40 bar，超过 0.30 bar"}},
+		{"未配置阈值时只检查 TPMS 警告", config.TiresWatchConfig{}, testStatus(t, tires(2.9, 2.9, 2.9, 1.0), tpmsWarningRR),
+			[]string{"⚠️ 右后: TPMS 胎压警告"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTiresWatcher(1, tt.cfg, newTestStore(t))
+			got := w.tireIssues(tireReadings(tt.status.Data.Status.TPMSDetails), tt.status.Data.Units.UnitOfPressure)
+			if len(got) != len(tt.want) {
+				t.Fatalf("问题 = %q, 期望 %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("问题 = %q, 期望 %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"teslamate-bot/models"
+	"teslamate-bot/store"
+)
+
+// versionState 软件版本监控的持久化状态
+type versionState struct {
+	Version         string `json:"version"`
+	UpdateAvailable bool   `json:"update_available,omitempty"`
+	AnnouncedUpdate string `json:"announced_update,omitempty"` // 已通知过的待安装版本
+}
+
+// newUpdate 记录可用更新的目标版本（可能未知），返回是否需要通知：
+// 更新从无到有时通知（同一目标版本再次出现不重复通知），
+// 更新期间目标版本变为另一个版本时也通知
+func (s *versionState) newUpdate(target string) bool {
+	announced := s.AnnouncedUpdate
+	if target != "" {
+		s.AnnouncedUpdate = target
+	}
+	if !s.UpdateAvailable {
+		return target == "" || target != announced
+	}
+	return target != "" && announced != "" && target != announced
+}
+
+// versionWatcher 监控软件更新的推送与安装
+type versionWatcher struct {
+	key   string
+	store *store.Store
+	state versionState
+}
+
+// newVersionWatcher 创建软件版本监控项并恢复上次保存的状态
+func newVersionWatcher(carID int, st *store.Store) *versionWatcher {
+	w := &versionWatcher{key: carKey("version", carID), store: st}
+	if _, err := st.Get(w.Name(), &w.state); err != nil {
+		log.Printf("恢复版本监控状态失败: %v", err)
+	}
+	return w
+}
+
+// Name 监控项名称
+func (w *versionWatcher) Name() string {
+	return w.key
+}
+
+// Check 有新版本可用时通知一次（目标版本未知时同样通知），版本号变化（安装完成）时再通知一次
+func (w *versionWatcher) Check(ctx context.Context, statusResp *models.StatusResponse) []Notification {
+	status := &statusResp.Data.Status
+	versions := status.CarVersions
+	if versions.Version == "" {
+		return nil
+	}
+
+	prev := w.state
+	var messages []Notification
+
+	switch {
+	case prev.Version == "":
+		// 首次运行只记录基线，不推送
+		w.state.Version = versions.Version
+		w.state.UpdateAvailable = versions.UpdateAvailable
+		if versions.UpdateAvailable {
+			w.state.AnnouncedUpdate = versions.UpdateVersion
+		}
+
+	case versions.Version != prev.Version:
+		w.state = versionState{Version: versions.Version}
+		messages = append(messages, Notification{
+			Topic: "update",
+			Text: fmt.Sprintf("📲 软件更新已安装\n━━━━━━━━━━━━━━━━━━━━\n🚗 %s\n📦 版本: %s → %s",
+				status.DisplayName, prev.Version, versions.Version),
+		})
+	}
+
+	if versions.UpdateAvailable && w.state.newUpdate(versions.UpdateVersion) {
+		target := versions.UpdateVersion
+		if target == "" {
+			target = "版本未知"
+		}
+		messages = append(messages, Notification{
+			Topic: "update",
+			Text: fmt.Sprintf("🆕 有可用的软件更新\n━━━━━━━━━━━━━━━━━━━━\n🚗 %s\n📦 当前版本: %s\n⬆️ 目标版本: %s",
+				status.DisplayName, versions.Version, target),
+		})
+	}
+	w.state.UpdateAvailable = versions.UpdateAvailable
+
+	if w.state != prev {
+		if err := w.store.Put(w.Name(), w.state); err != nil {
+			log.Printf("保存版本监控状态失败: %v", err)
+		}
+	}
+
+	return messages
+}
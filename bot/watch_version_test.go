@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"teslamate-bot/models"
+)
+
+// carVersion 当前版本与待安装版本（为空表示没有可用更新）
+func carVersion(version, update string) func(s *models.CarStatus) {
+	return func(s *models.CarStatus) {
+		s.CarVersions = models.CarVersions{Version: version, UpdateAvailable: update != "", UpdateVersion: update}
+	}
+}
+
+func TestVersionWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "teslamate-bot.db")
+	st := openTestStore(t, path)
+
+	runSteps(t, newVersionWatcher(1, st), []checkStep{
+		// 首次运行只记录基线（包括已有的可用更新）
+		{status: testStatus(t, carVersion("2024.14.6", "2024.14.9"))},
+		{status: testStatus(t, carVersion("2024.14.6", "2024.14.9"))},
+		// 没有版本数据时保持原状态
+		{status: testStatus(t, carVersion("", ""))},
+		{status: testStatus(t, carVersion("2024.14.9", "")), want: []string{"update: 📲 软件更新已安装"}},
+		{status: testStatus(t, carVersion("2024.14.9", "2024.20.1")), want: []string{"update: 🆕 有可用的软件更新"}},
+		// 同一个更新只通知一次
+		{status: testStatus(t, carVersion("2024.14.9", "2024.20.1"))},
+		{status: testStatus(t, carVersion("2024.14.9", ""))},
+		{status: testStatus(t, carVersion("2024.14.9", "2024.20.1"))},
+		// 更新期间目标版本变化时再通知
+		{status: testStatus(t, carVersion("2024.14.9", "2024.20.2")), want: []string{"update: 🆕 有可用的软件更新"}},
+	})
+
+	// 重启后同样不重复通知
+	st.Close()
+	runSteps(t, newVersionWatcher(1, openTestStore(t, path)), []checkStep{
+		{status: testStatus(t, carVersion("2024.14.9", "2024.20.2"))},
+		{status: testStatus(t, carVersion("2024.20.2", "2024.26.3")), want: []string{
+			"update: 📲 软件更新已安装",
+			"update: 🆕 有可用的软件更新",
+		}},
+	})
+}
+
+func TestVersionWatcherUnknownTarget(t *testing.T) {
+	unknown := func(s *models.CarStatus) {
+		s.CarVersions = models.CarVersions{Version: "2024.14.9", UpdateAvailable: true}
+	}
+	w := newVersionWatcher(1, newTestStore(t))
+	runSteps(t, w, []checkStep{
+		{status: testStatus(t, carVersion("2024.14.9", ""))},
+		// 没有目标版本时同样在更新出现时通知
+		{status: testStatus(t, unknown), want: []string{"update: 🆕 有可用的软件更新"}},
+		{status: testStatus(t, unknown)},
+		// 之后获得目标版本不重复通知
+		{status: testStatus(t, carVersion("2024.14.9", "2024.20.1"))},
+		{status: testStatus(t, carVersion("2024.14.9", ""))},
+		{status: testStatus(t, unknown), want: []string{"update: 🆕 有可用的软件更新"}},
+	})
+
+	w.Check(context.Background(), testStatus(t, carVersion("2024.14.9", "")))
+	notes := w.Check(context.Background(), testStatus(t, unknown))
+	if len(notes) != 1 || !strings.Contains(notes[0].Text, "目标版本: 版本未知") {
+		t.Errorf("通知 = %v, 期望目标版本显示为版本未知", notes)
+	}
+}
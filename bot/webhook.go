@@ -0,0 +1,128 @@
+package bot
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"teslamate-bot/config"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// secretTokenHeader Telegram 推送更新时携带 secret_token 的请求头
+	secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+	// webhookShutdownTimeout 停止 Webhook 服务器时等待请求处理完成的时长
+	webhookShutdownTimeout = 10 * time.Second
+)
+
+// webhookServer 接收 Telegram 推送更新的 HTTP 服务器
+type webhookServer struct {
+	cfg     config.WebhookConfig
+	server  *http.Server
+	updates chan tgbotapi.Update
+	done    chan struct{} // 停止时关闭，不再接收新的更新
+}
+
+// startWebhook 注册 Webhook 并开始监听，返回更新通道
+func (b *Bot) startWebhook(cfg config.WebhookConfig) (tgbotapi.UpdatesChannel, error) {
+	listener, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return nil, fmt.Errorf("Webhook 监听 %s 失败: %w", cfg.Listen, err)
+	}
+
+	w := &webhookServer{
+		cfg:     cfg,
+		updates: make(chan tgbotapi.Update, b.api.Buffer),
+		done:    make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(cfg.Path, func(rw http.ResponseWriter, r *http.Request) {
+		w.serveUpdate(b.api, rw, r)
+	})
+	w.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	params := tgbotapi.Params{
+		"url":             cfg.URL,
+		"allowed_updates": `["message","callback_query"]`,
+	}
+	params["secret_token"] = cfg.SecretToken
+	if _, err := b.api.MakeRequest("setWebhook", params); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("设置 Webhook 失败: %w", err)
+	}
+
+	go func() {
+		var err error
+		if cfg.CertFile != "" {
+			err = w.server.ServeTLS(listener, cfg.CertFile, cfg.KeyFile)
+		} else {
+			err = w.server.Serve(listener)
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Webhook 服务器异常退出: %v", err)
+		}
+	}()
+
+	b.webhook = w
+	log.Printf("Webhook 已启动: %s（监听 %s%s）", cfg.URL, cfg.Listen, cfg.Path)
+	return w.updates, nil
+}
+
+// serveUpdate 校验并解析一次更新推送
+func (w *webhookServer) serveUpdate(api *tgbotapi.BotAPI, rw http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get(secretTokenHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(w.cfg.SecretToken)) != 1 {
+		log.Printf("Webhook 请求 secret token 校验失败: %s", r.RemoteAddr)
+		http.Error(rw, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	update, err := api.HandleUpdate(r)
+	if err != nil {
+		log.Printf("解析 Webhook 更新失败: %v", err)
+		http.Error(rw, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	// 停止后返回错误，Telegram 会保留该更新并在下次设置 Webhook 后重新推送
+	select {
+	case <-w.done:
+		http.Error(rw, "shutting down", http.StatusServiceUnavailable)
+		return
+	default:
+	}
+	select {
+	case w.updates <- *update:
+		rw.WriteHeader(http.StatusOK)
+	case <-w.done:
+		http.Error(rw, "shutting down", http.StatusServiceUnavailable)
+	}
+}
+
+// stopWebhook 删除 Webhook 并关闭服务器，等待处理中的请求完成后关闭更新通道
+//
+// 超时仍有请求未完成时不关闭更新通道（这些请求可能仍在发送），
+// 由 done 通知请求放弃发送，消费方在退出截止时间后自行停止读取。
+func (b *Bot) stopWebhook() {
+	w := b.webhook
+	if _, err := b.api.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		log.Printf("删除 Webhook 失败: %v", err)
+	}
+
+	close(w.done)
+	ctx, cancel := context.WithTimeout(context.Background(), webhookShutdownTimeout)
+	defer cancel()
+	if err := w.server.Shutdown(ctx); err != nil {
+		log.Printf("关闭 Webhook 服务器失败: %v", err)
+		return
+	}
+	close(w.updates)
+	log.Println("Webhook 已停止")
+}
@@ -0,0 +1,50 @@
+package bot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"teslamate-bot/config"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestWebhookServeUpdate(t *testing.T) {
+	w := &webhookServer{
+		cfg:     config.WebhookConfig{SecretToken: "s3cret"},
+		updates: make(chan tgbotapi.Update, 1),
+		done:    make(chan struct{}),
+	}
+	serve := func(token string) int {
+		r := httptest.NewRequest(http.MethodPost, "/telegram/webhook", strings.NewReader(`{"update_id": 7}`))
+		if token != "" {
+			r.Header.Set(secretTokenHeader, token)
+		}
+		rec := httptest.NewRecorder()
+		w.serveUpdate(&tgbotapi.BotAPI{}, rec, r)
+		return rec.Code
+	}
+
+	for _, token := range []string{"", "wrong"} {
+		if code := serve(token); code != http.StatusForbidden {
+			t.Errorf("secret token %q: 状态码 = %d, 期望 403", token, code)
+		}
+	}
+	if code := serve("s3cret"); code != http.StatusOK {
+		t.Fatalf("状态码 = %d, 期望 200", code)
+	}
+	if update := <-w.updates; update.UpdateID != 7 {
+		t.Errorf("收到更新 %d, 期望 7", update.UpdateID)
+	}
+
+	// 停止后即使通道有空间也不再接收，由 Telegram 之后重新推送
+	close(w.done)
+	if code := serve("s3cret"); code != http.StatusServiceUnavailable {
+		t.Errorf("停止后状态码 = %d, 期望 503", code)
+	}
+	if len(w.updates) != 0 {
+		t.Error("停止后仍向更新通道发送")
+	}
+}
@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// updateQueueSize 每个工作协程的队列长度，队列满时暂停接收新的更新
+	updateQueueSize = 64
+	// depthLogInterval 记录更新队列深度的间隔
+	depthLogInterval = 5 * time.Minute
+)
+
+// updatePool 并发处理更新的工作池
+//
+// 更新按会话分配到固定的工作协程，同一会话的更新按接收顺序处理，
+// 慢请求只会阻塞同一工作协程上的会话。
+type updatePool struct {
+	queues []chan tgbotapi.Update
+	handle func(tgbotapi.Update)
+	depth  atomic.Int64 // 已提交但尚未处理完的更新数量
+	wg     sync.WaitGroup
+	stop   chan struct{}
+
+	mu   sync.Mutex
+	peak int64 // 上次记录以来的最大队列深度
+}
+
+// newUpdatePool 创建工作池并启动 workers 个工作协程
+func newUpdatePool(workers int, handle func(tgbotapi.Update)) *updatePool {
+	p := &updatePool{
+		queues: make([]chan tgbotapi.Update, max(workers, 1)),
+		handle: handle,
+		stop:   make(chan struct{}),
+	}
+	for i := range p.queues {
+		p.queues[i] = make(chan tgbotapi.Update, updateQueueSize)
+		p.wg.Add(1)
+		go p.work(p.queues[i])
+	}
+	go p.logDepth(depthLogInterval)
+	return p
+}
+
+// work 依次处理一个队列中的更新
+func (p *updatePool) work(queue <-chan tgbotapi.Update) {
+	defer p.wg.Done()
+	for update := range queue {
+		p.handle(update)
+		p.depth.Add(-1)
+	}
+}
+
+// Submit 提交更新，队列已满时等待（退出时已接收的更新也需要处理，因此不会放弃）
+func (p *updatePool) Submit(update tgbotapi.Update) {
+	depth := p.depth.Add(1)
+	p.mu.Lock()
+	p.peak = max(p.peak, depth)
+	p.mu.Unlock()
+	p.queues[p.shard(update)] <- update
+}
+
+// Depth 已提交但尚未处理完的更新数量
+func (p *updatePool) Depth() int {
+	return int(p.depth.Load())
+}
+
+// Close 停止接收更新并等待已提交的更新处理完成
+func (p *updatePool) Close() {
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.wg.Wait()
+	close(p.stop)
+}
+
+// shard 更新所属的工作协程（按会话分配，没有会话时按用户分配）
+func (p *updatePool) shard(update tgbotapi.Update) int {
+	var id int64
+	if chat := update.FromChat(); chat != nil {
+		id = chat.ID
+	} else if user := update.SentFrom(); user != nil {
+		id = user.ID
+	}
+	return int(uint64(id) % uint64(len(p.queues)))
+}
+
+// logDepth 按固定间隔记录队列深度，直到工作池关闭
+func (p *updatePool) logDepth(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			depth, peak := p.sample()
+			log.Printf("更新队列: %d 条待处理，近 %d 分钟最多 %d 条（%d 个工作协程）", depth, int(interval.Minutes()), peak, len(p.queues))
+		}
+	}
+}
+
+// sample 返回当前队列深度与上次采样以来的最大深度，并重新开始统计最大深度
+func (p *updatePool) sample() (depth, peak int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	current := p.depth.Load()
+	peak = int(max(p.peak, current))
+	p.peak = current
+	return int(current), peak
+}
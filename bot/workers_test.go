@@ -0,0 +1,113 @@
+package bot
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// chatUpdate 来自会话 chatID 的第 id 条更新
+func chatUpdate(chatID int64, id int) tgbotapi.Update {
+	return tgbotapi.Update{
+		UpdateID: id,
+		Message:  &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: chatID}},
+	}
+}
+
+func TestUpdatePoolOrderPerChat(t *testing.T) {
+	var mu sync.Mutex
+	got := make(map[int64][]int)
+	pool := newUpdatePool(4, func(update tgbotapi.Update) {
+		mu.Lock()
+		defer mu.Unlock()
+		chatID := update.FromChat().ID
+		got[chatID] = append(got[chatID], update.UpdateID)
+	})
+
+	for i := range 100 {
+		pool.Submit(chatUpdate(int64(i%5), i))
+	}
+	pool.Close()
+
+	for chatID, ids := range got {
+		if len(ids) != 20 {
+			t.Errorf("会话 %d 处理了 %d 条更新, 期望 20", chatID, len(ids))
+		}
+		for i := 1; i < len(ids); i++ {
+			if ids[i] < ids[i-1] {
+				t.Errorf("会话 %d 的处理顺序 = %v, 期望按接收顺序", chatID, ids)
+				break
+			}
+		}
+	}
+}
+
+func TestUpdatePoolConcurrentChats(t *testing.T) {
+	// 会话 1 的处理等待会话 2 的处理开始，两者在不同工作协程上才能完成
+	chat2 := make(chan struct{})
+	pool := newUpdatePool(2, func(update tgbotapi.Update) {
+		switch update.FromChat().ID {
+		case 1:
+			select {
+			case <-chat2:
+			case <-time.After(5 * time.Second):
+				t.Error("会话 1 的慢请求阻塞了会话 2")
+			}
+		case 2:
+			close(chat2)
+		}
+	})
+
+	pool.Submit(chatUpdate(1, 1))
+	pool.Submit(chatUpdate(2, 2))
+	pool.Close()
+}
+
+func TestUpdatePoolCloseDrains(t *testing.T) {
+	var mu sync.Mutex
+	handled := 0
+	pool := newUpdatePool(2, func(update tgbotapi.Update) {
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		handled++
+		mu.Unlock()
+	})
+
+	// 队列已满时 Submit 等待而不是丢弃
+	n := 2*updateQueueSize + 10
+	for i := range n {
+		pool.Submit(chatUpdate(int64(i%2), i))
+	}
+	pool.Close()
+
+	if handled != n || pool.Depth() != 0 {
+		t.Errorf("Close 后处理了 %d 条更新（待处理 %d）, 期望 %d 条全部处理", handled, pool.Depth(), n)
+	}
+}
+
+func TestUpdatePoolSample(t *testing.T) {
+	release := make(chan struct{})
+	pool := newUpdatePool(1, func(tgbotapi.Update) { <-release })
+
+	for i := range 3 {
+		pool.Submit(chatUpdate(1, i))
+	}
+	if depth, peak := pool.sample(); depth != 3 || peak != 3 {
+		t.Errorf("sample() = %d, %d, 期望 3, 3", depth, peak)
+	}
+
+	// 处理完成后最大深度保留到下一次采样
+	close(release)
+	for pool.Depth() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if depth, peak := pool.sample(); depth != 0 || peak != 3 {
+		t.Errorf("处理完成后 sample() = %d, %d, 期望 0, 3", depth, peak)
+	}
+	if depth, peak := pool.sample(); depth != 0 || peak != 0 {
+		t.Errorf("再次采样 sample() = %d, %d, 期望 0, 0", depth, peak)
+	}
+	pool.Close()
+}
@@ -0,0 +1,25 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"teslamate-bot/models"
+)
+
+// TeslaMateAPI Bot 使用的 TeslaMate API 操作（测试中可使用 teslamatetest 的模拟服务器）
+type TeslaMateAPI interface {
+	GetCars(ctx context.Context) ([]models.Car, error)
+	GetCarDetails(ctx context.Context, carID int) (*models.Car, error)
+	GetCarStatus(ctx context.Context, carID int) (*models.StatusResponse, error)
+	GetBatteryHealth(ctx context.Context, carID int) (*models.BatteryHealthResponse, error)
+	GetLatestCharge(ctx context.Context, carID int) (*models.Charge, error)
+	GetCharges(ctx context.Context, carID int, start, end time.Time) ([]models.Charge, error)
+	GetLatestDrive(ctx context.Context, carID int) (*models.Drive, *models.Units, error)
+	GetDrives(ctx context.Context, carID int, start, end time.Time) ([]models.Drive, *models.Units, error)
+	GetUpdates(ctx context.Context, carID int) ([]models.Update, error)
+	SendCommand(ctx context.Context, carID int, command Command, payload any) error
+	Close()
+}
+
+var _ TeslaMateAPI = (*Client)(nil)
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheEntry 一条缓存的响应
+type cacheEntry struct {
+	body    []byte
+	fetched time.Time
+	expires time.Time
+}
+
+// cacheTTL 各接口响应的缓存时长
+func cacheTTL(path string) time.Duration {
+	p, _, _ := strings.Cut(path, "?")
+	switch {
+	case strings.HasSuffix(p, "/status"):
+		return 10 * time.Second
+	case strings.HasSuffix(p, "/charges"), strings.HasSuffix(p, "/drives"), strings.HasSuffix(p, "/updates"):
+		return time.Minute
+	case strings.HasSuffix(p, "/battery-health"):
+		return 10 * time.Minute
+	}
+	// 车辆列表与车辆详情
+	return 5 * time.Minute
+}
+
+// get 执行 GET 请求
+//
+// 优先返回未过期的缓存，同一路径的并发请求只发送一次。请求在后台完成，
+// 调用方的 ctx 取消只会让该调用方提前返回，不影响共享同一请求的其他调用方；
+// 共享的请求在 Client 关闭时取消。
+func (c *Client) get(ctx context.Context, path string) ([]byte, error) {
+	if !bypassCache(ctx) {
+		if entry, ok := c.cached(path, time.Now()); ok {
+			recordFetched(ctx, entry.fetched)
+			return entry.body, nil
+		}
+	}
+
+	ch := c.flight.DoChan(path, func() (any, error) {
+		shared, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		defer cancel()
+		stop := context.AfterFunc(c.ctx, cancel)
+		defer stop()
+
+		body, err := c.doRequest(shared, "GET", path, nil)
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		entry := cacheEntry{body: body, fetched: now, expires: now.Add(cacheTTL(path))}
+		c.storeCache(path, entry)
+		return entry, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, requestError(ctx.Err())
+	case result := <-ch:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		entry := result.Val.(cacheEntry)
+		recordFetched(ctx, entry.fetched)
+		return entry.body, nil
+	}
+}
+
+// cached 获取未过期的缓存
+func (c *Client) cached(path string, now time.Time) (cacheEntry, bool) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+
+	entry, ok := c.cache[path]
+	if !ok || !now.Before(entry.expires) {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// storeCache 写入缓存并清理已过期的条目
+func (c *Client) storeCache(path string, entry cacheEntry) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+
+	for key, e := range c.cache {
+		if !entry.fetched.Before(e.expires) {
+			delete(c.cache, key)
+		}
+	}
+	c.cache[path] = entry
+}
+
+// invalidate 删除路径以 prefix 开头的缓存（车辆状态因远程操作改变时使用）
+func (c *Client) invalidate(prefix string) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+
+	for key := range c.cache {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.cache, key)
+		}
+	}
+}
+
+// cacheCtxKey context 中缓存选项的键
+type cacheCtxKey int
+
+const (
+	bypassKey cacheCtxKey = iota
+	cacheInfoKey
+)
+
+// WithoutCache 返回忽略缓存的 context（用于用户主动刷新），获取到的数据仍会写入缓存
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey, true)
+}
+
+// bypassCache 是否忽略缓存
+func bypassCache(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassKey).(bool)
+	return bypass
+}
+
+// CacheInfo 记录一组请求所用数据的获取时间
+type CacheInfo struct {
+	mu     sync.Mutex
+	oldest time.Time
+}
+
+// WithCacheInfo 返回记录数据获取时间的 context
+func WithCacheInfo(ctx context.Context) (context.Context, *CacheInfo) {
+	info := &CacheInfo{}
+	return context.WithValue(ctx, cacheInfoKey, info), info
+}
+
+// Age 所用数据中最旧一份距今的时长（没有请求时为 0）
+func (i *CacheInfo) Age(now time.Time) time.Duration {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.oldest.IsZero() {
+		return 0
+	}
+	return now.Sub(i.oldest)
+}
+
+// recordFetched 记录数据的获取时间
+func recordFetched(ctx context.Context, fetched time.Time) {
+	info, ok := ctx.Value(cacheInfoKey).(*CacheInfo)
+	if !ok {
+		return
+	}
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	if info.oldest.IsZero() || fetched.Before(info.oldest) {
+		info.oldest = fetched
+	}
+}
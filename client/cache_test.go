@@ -0,0 +1,79 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"teslamate-bot/client"
+)
+
+// newBlockingServer 收到请求后阻塞直到测试结束的服务器，started 在收到请求时发送
+func newBlockingServer(t *testing.T) (*httptest.Server, <-chan struct{}) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+	t.Cleanup(func() {
+		close(release)
+		srv.Close()
+	})
+	return srv, started
+}
+
+func TestCloseCancelsSharedRequests(t *testing.T) {
+	srv, started := newBlockingServer(t)
+	c := client.NewClient(srv.URL, "", 30, nil)
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := c.GetCars(context.Background())
+		errs <- err
+	}()
+	<-started
+
+	c.Close()
+	select {
+	case err := <-errs:
+		var apiErr *client.Error
+		if !errors.As(err, &apiErr) || apiErr.Kind != client.KindCanceled {
+			t.Errorf("Close 后请求错误 = %v, 期望已取消", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close 后共享请求仍未结束")
+	}
+}
+
+func TestCallerCancelDoesNotCancelSharedRequest(t *testing.T) {
+	srv, started := newBlockingServer(t)
+	c := client.NewClient(srv.URL, "", 30, nil)
+	defer c.Close()
+
+	// 一个调用方取消只让它自己提前返回
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() {
+		_, err := c.GetCars(ctx)
+		errs <- err
+	}()
+	<-started
+	go func() {
+		_, err := c.GetCars(context.Background())
+		errs <- err
+	}()
+
+	cancel()
+	if err := <-errs; err == nil {
+		t.Fatal("取消的调用方未返回错误")
+	}
+	select {
+	case err := <-errs:
+		t.Fatalf("共享请求随调用方取消: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
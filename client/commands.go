@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"teslamate-bot/models"
+)
+
+// Command 车辆远程指令（TeslaMateApi 的 /command/{command} 端点）
+type Command string
+
+// 支持的远程指令
+const (
+	CommandWakeUp              Command = "wake_up"
+	CommandDoorLock            Command = "door_lock"
+	CommandDoorUnlock          Command = "door_unlock"
+	CommandClimateStart        Command = "auto_conditioning_start"
+	CommandClimateStop         Command = "auto_conditioning_stop"
+	CommandChargeStart         Command = "charge_start"
+	CommandChargeStop          Command = "charge_stop"
+	CommandChargePortDoorOpen  Command = "charge_port_door_open"
+	CommandChargePortDoorClose Command = "charge_port_door_close"
+	CommandSetChargeLimit      Command = "set_charge_limit"
+	CommandSetSentryMode       Command = "set_sentry_mode"
+	CommandHonkHorn            Command = "honk_horn"
+	CommandFlashLights         Command = "flash_lights"
+)
+
+// ChargeLimitPayload set_charge_limit 指令参数
+type ChargeLimitPayload struct {
+	Percent int `json:"percent"`
+}
+
+// SentryModePayload set_sentry_mode 指令参数
+type SentryModePayload struct {
+	On bool `json:"on"`
+}
+
+// SendCommand 向车辆发送远程指令（payload 为 nil 时不带参数）
+//
+// 需要在 TeslaMateApi 中开启 ENABLE_COMMANDS，并允许对应指令。
+func (c *Client) SendCommand(ctx context.Context, carID int, command Command, payload any) error {
+	path := fmt.Sprintf("/api/v1/cars/%d/command/%s", carID, command)
+	if command == CommandWakeUp {
+		// 唤醒使用单独的端点
+		path = fmt.Sprintf("/api/v1/cars/%d/wake_up", carID)
+	}
+
+	var reqBody []byte
+	if payload != nil {
+		var err error
+		reqBody, err = json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("编码指令参数失败: %w", err)
+		}
+	}
+
+	body, err := c.doRequest(ctx, "POST", path, reqBody)
+	if err != nil {
+		return fmt.Errorf("发送指令 %s 失败: %w", command, err)
+	}
+	// 指令可能改变车辆状态，之后的查询重新获取
+	c.invalidate(fmt.Sprintf("/api/v1/cars/%d/", carID))
+
+	// 唤醒指令返回车辆数据，不含执行结果
+	if command == CommandWakeUp {
+		return nil
+	}
+
+	var response models.CommandResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return decodeError("解析指令响应失败", err)
+	}
+	if !response.Response.Result {
+		reason := response.Response.Reason
+		kind := KindRejected
+		if strings.Contains(reason, "unavailable") || strings.Contains(reason, "asleep") {
+			kind = KindCarUnavailable
+		}
+		return &Error{Kind: kind, Message: fmt.Sprintf("车辆未执行指令 %s", command), Body: reason}
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ErrorKind API 错误类型
+type ErrorKind int
+
+const (
+	KindUnknown        ErrorKind = iota
+	KindUnauthorized             // API 密钥无效或缺失（401/403）
+	KindNotFound                 // 车辆或数据不存在
+	KindCarUnavailable           // 车辆休眠或离线，无法执行指令
+	KindTimeout                  // 请求超时
+	KindDecode                   // 响应无法解析
+	KindUpstream                 // TeslaMate API 或其上游返回 5xx
+	KindUnavailable              // 熔断中，未发送请求
+	KindNetwork                  // 无法连接 TeslaMate API
+	KindCanceled                 // 请求被取消
+	KindBadRequest               // 其他 4xx（请求参数错误、限流等）
+	KindRejected                 // 车辆拒绝执行指令
+)
+
+// String 错误类型名称（用于日志）
+func (k ErrorKind) String() string {
+	switch k {
+	case KindUnauthorized:
+		return "unauthorized"
+	case KindNotFound:
+		return "not_found"
+	case KindCarUnavailable:
+		return "car_unavailable"
+	case KindTimeout:
+		return "timeout"
+	case KindDecode:
+		return "decode"
+	case KindUpstream:
+		return "upstream"
+	case KindUnavailable:
+		return "unavailable"
+	case KindNetwork:
+		return "network"
+	case KindCanceled:
+		return "canceled"
+	case KindBadRequest:
+		return "bad_request"
+	case KindRejected:
+		return "rejected"
+	}
+	return "unknown"
+}
+
+// Error TeslaMate API 错误，调用方可通过 errors.As 获取错误类型
+//
+// Error() 包含状态码与响应内容等完整信息，只应写入日志；
+// 展示给用户时根据 Kind 选择提示。
+type Error struct {
+	Kind       ErrorKind
+	Message    string        // 错误描述
+	StatusCode int           // HTTP 状态码（没有响应时为 0）
+	Body       string        // 响应内容（车辆拒绝执行指令时为原因）
+	RetryAfter time.Duration // 响应中的 Retry-After（没有时为 0）
+	Err        error         // 底层错误
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	switch {
+	case e.StatusCode != 0:
+		msg = fmt.Sprintf("API返回错误状态码: %d, 响应: %s", e.StatusCode, e.Body)
+	case e.Body != "":
+		msg += ": " + e.Body
+	}
+	if e.Err != nil {
+		return msg + ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// statusError 根据状态码生成错误
+func statusError(code int, body string, retryAfter time.Duration) *Error {
+	kind := KindBadRequest
+	switch {
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		kind = KindUnauthorized
+	case code == http.StatusNotFound:
+		kind = KindNotFound
+	case code == http.StatusRequestTimeout || strings.Contains(body, "vehicle unavailable"):
+		// Tesla API 在车辆休眠或离线时返回 408 vehicle unavailable
+		kind = KindCarUnavailable
+	case code >= 500:
+		kind = KindUpstream
+	}
+	return &Error{Kind: kind, StatusCode: code, Body: body, RetryAfter: retryAfter}
+}
+
+// requestError 包装请求过程中的错误（超时、取消或网络错误）
+func requestError(err error) *Error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return &Error{Kind: KindCanceled, Message: "请求已取消", Err: err}
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, fasthttp.ErrTimeout), errors.Is(err, fasthttp.ErrDialTimeout), errors.Is(err, os.ErrDeadlineExceeded):
+		return &Error{Kind: KindTimeout, Message: "请求超时", Err: err}
+	}
+	return &Error{Kind: KindNetwork, Message: "请求失败", Err: err}
+}
+
+// decodeError 包装解析响应失败的错误
+func decodeError(message string, err error) *Error {
+	return &Error{Kind: KindDecode, Message: message, Err: err}
+}
+
+// notFoundError 没有数据时的错误（message 可以直接展示给用户）
+func notFoundError(message string) *Error {
+	return &Error{Kind: KindNotFound, Message: message}
+}
@@ -0,0 +1,219 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryPolicy 查询请求的重试策略
+type RetryPolicy struct {
+	MaxRetries int           // 最大重试次数（0 表示不重试）
+	Backoff    time.Duration // 首次重试前的等待时间，之后每次翻倍
+	MaxBackoff time.Duration // 单次等待时间上限，Retry-After 超过该值时不再重试
+}
+
+// BreakerPolicy 熔断策略
+type BreakerPolicy struct {
+	FailureThreshold int           // 连续失败该次数后熔断（0 表示不熔断）
+	Cooldown         time.Duration // 熔断后等待多久再放行一次试探请求
+}
+
+// Option 客户端可选配置
+type Option func(*Client)
+
+// WithRetry 设置重试策略
+func WithRetry(p RetryPolicy) Option {
+	return func(c *Client) { c.retry = p }
+}
+
+// WithBreaker 设置熔断策略
+func WithBreaker(p BreakerPolicy) Option {
+	return func(c *Client) { c.breaker.policy = p }
+}
+
+// retryable 是否可以重试（网络错误、超时及可重试的状态码）
+func retryable(err *Error) bool {
+	switch err.Kind {
+	case KindNetwork, KindTimeout:
+		return true
+	case KindUpstream, KindBadRequest:
+		return retryableStatus(err.StatusCode)
+	}
+	return false
+}
+
+// retryableStatus 可以重试的状态码（限流、网关错误及 Cloudflare 52x）
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return code >= 520 && code <= 524
+}
+
+// parseRetryAfter 解析 Retry-After 响应头（秒数或 HTTP 日期）
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// delay 第 attempt 次重试前的等待时间（指数退避加随机抖动），返回 false 表示不应再重试
+func (p RetryPolicy) delay(attempt int, retryAfter time.Duration) (time.Duration, bool) {
+	// 先与上限比较再移位，重试次数较多时不会溢出
+	backoff := p.MaxBackoff
+	if attempt < 63 && p.Backoff <= p.MaxBackoff>>attempt {
+		backoff = p.Backoff << attempt
+	}
+	// 在 [backoff/2, backoff] 之间随机，避免多个请求同时重试
+	if half := backoff / 2; half > 0 {
+		backoff = half + rand.N(half+1)
+	}
+	if retryAfter > 0 {
+		if retryAfter > p.MaxBackoff {
+			return 0, false
+		}
+		backoff = max(backoff, retryAfter)
+	}
+	return backoff, true
+}
+
+// doRequest 执行HTTP请求（body 为 nil 时不带请求体）
+//
+// 查询请求在网络错误或可重试的状态码时按重试策略重试；远程控制指令不是幂等的，
+// 只发送一次。熔断期间不发送请求，直接返回 KindUnavailable 错误。
+func (c *Client) doRequest(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	retries := 0
+	if method == http.MethodGet {
+		retries = max(c.retry.MaxRetries, 0)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := c.breaker.allow(time.Now()); err != nil {
+			return nil, err
+		}
+		respBody, err := c.send(ctx, method, path, body)
+		c.breaker.record(err, time.Now())
+		if err == nil {
+			return respBody, nil
+		}
+
+		if !retryable(err) || attempt >= retries {
+			return nil, err
+		}
+		wait, ok := c.retry.delay(attempt, err.RetryAfter)
+		if !ok {
+			return nil, err
+		}
+
+		log.Printf("TeslaMate API 请求失败，%s 后重试（%d/%d）: %s %s: %v", wait.Round(time.Millisecond), attempt+1, retries, method, path, err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, requestError(ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// circuitBreaker 熔断器：连续失败达到阈值后在冷却期内拒绝请求，
+// 冷却结束后放行一次试探请求，成功则恢复，失败则继续熔断
+type circuitBreaker struct {
+	mu        sync.Mutex
+	policy    BreakerPolicy
+	failures  int
+	open      bool
+	openUntil time.Time // 冷却结束时间
+	probing   bool
+	onChange  func(available bool)
+}
+
+// allow 判断是否可以发送请求
+func (b *circuitBreaker) allow(now time.Time) *Error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return nil
+	}
+	if now.Before(b.openUntil) {
+		wait := int(b.openUntil.Sub(now).Seconds()) + 1
+		return &Error{Kind: KindUnavailable, Message: fmt.Sprintf("TeslaMate API 暂不可用（%d 秒后重试）", wait)}
+	}
+	if b.probing {
+		return &Error{Kind: KindUnavailable, Message: "TeslaMate API 暂不可用"}
+	}
+	b.probing = true
+	return nil
+}
+
+// record 记录一次请求结果（请求被取消时不计入）
+func (b *circuitBreaker) record(err *Error, now time.Time) {
+	if b.policy.FailureThreshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	b.probing = false
+	if err != nil && err.Kind == KindCanceled {
+		b.mu.Unlock()
+		return
+	}
+
+	// 服务端有正常响应（包括 4xx）说明 API 可用
+	failed := false
+	if err != nil {
+		switch err.Kind {
+		case KindNetwork, KindTimeout, KindUpstream:
+			failed = true
+		}
+	}
+
+	var changed, available bool
+	switch {
+	case !failed:
+		b.failures = 0
+		if b.open {
+			b.open = false
+			changed, available = true, true
+			log.Println("TeslaMate API 已恢复，熔断结束")
+		}
+	case b.open:
+		b.openUntil = now.Add(b.policy.Cooldown)
+	default:
+		b.failures++
+		if b.failures >= b.policy.FailureThreshold {
+			b.open = true
+			b.openUntil = now.Add(b.policy.Cooldown)
+			changed, available = true, false
+			log.Printf("TeslaMate API 连续失败 %d 次，熔断 %s: %v", b.failures, b.policy.Cooldown, err)
+		}
+	}
+	onChange := b.onChange
+	b.mu.Unlock()
+
+	if changed && onChange != nil {
+		onChange(available)
+	}
+}
+
+// OnAvailabilityChange 设置熔断与恢复时的回调（available 为 false 表示开始熔断）
+func (c *Client) OnAvailabilityChange(fn func(available bool)) {
+	c.breaker.mu.Lock()
+	defer c.breaker.mu.Unlock()
+	c.breaker.onChange = fn
+}
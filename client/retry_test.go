@@ -0,0 +1,238 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedResponse 模拟服务器的一次响应
+type scriptedResponse struct {
+	status     int
+	retryAfter string
+}
+
+// scriptedServer 按顺序返回预设响应的服务器，响应用完后返回 200
+type scriptedServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	script   []scriptedResponse
+	requests []string
+}
+
+// newScriptedServer 创建按 script 响应的服务器
+func newScriptedServer(t *testing.T, script ...scriptedResponse) *scriptedServer {
+	s := &scriptedServer{script: script}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.Method)
+		resp := scriptedResponse{status: http.StatusOK}
+		if len(s.script) > 0 {
+			resp, s.script = s.script[0], s.script[1:]
+		}
+		s.mu.Unlock()
+
+		if resp.retryAfter != "" {
+			w.Header().Set("Retry-After", resp.retryAfter)
+		}
+		w.WriteHeader(resp.status)
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// count 收到的请求数
+func (s *scriptedServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests)
+}
+
+// status 只有状态码的响应
+func status(code int) scriptedResponse {
+	return scriptedResponse{status: code}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{MaxRetries: 5, Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	tests := []struct {
+		attempt    int
+		retryAfter time.Duration
+		min, max   time.Duration
+		ok         bool
+	}{
+		// 每次翻倍，在 [backoff/2, backoff] 之间随机
+		{0, 0, 50 * time.Millisecond, 100 * time.Millisecond, true},
+		{1, 0, 100 * time.Millisecond, 200 * time.Millisecond, true},
+		{3, 0, 400 * time.Millisecond, 800 * time.Millisecond, true},
+		// 超过上限时按上限计算
+		{5, 0, 500 * time.Millisecond, time.Second, true},
+		{40, 0, 500 * time.Millisecond, time.Second, true},
+		{100, 0, 500 * time.Millisecond, time.Second, true},
+		// Retry-After 更长时以其为准，超过上限时不再重试
+		{0, 900 * time.Millisecond, 900 * time.Millisecond, 900 * time.Millisecond, true},
+		{0, 2 * time.Second, 0, 0, false},
+	}
+	for _, tt := range tests {
+		for range 20 {
+			got, ok := p.delay(tt.attempt, tt.retryAfter)
+			if ok != tt.ok || got < tt.min || got > tt.max {
+				t.Errorf("delay(%d, %s) = %s, %v, 期望 [%s, %s], %v", tt.attempt, tt.retryAfter, got, ok, tt.min, tt.max, tt.ok)
+				break
+			}
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"0", 0},
+		{"-1", 0},
+		{"soon", 0},
+		{now.Add(10 * time.Second).Format(http.TimeFormat), 10 * time.Second},
+		{now.Add(-10 * time.Second).Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, 期望 %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestDoRequestRetry(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond, MaxBackoff: 2 * time.Second}
+	tests := []struct {
+		name     string
+		method   string
+		script   []scriptedResponse
+		wantErr  bool
+		requests int
+	}{
+		{"网关错误后重试成功", http.MethodGet, []scriptedResponse{status(503), status(502)}, false, 3},
+		{"超过重试次数", http.MethodGet, []scriptedResponse{status(503), status(504), status(522)}, true, 3},
+		{"不可重试的状态码", http.MethodGet, []scriptedResponse{status(404)}, true, 1},
+		{"内部错误不重试", http.MethodGet, []scriptedResponse{status(500)}, true, 1},
+		{"远程控制指令不重试", http.MethodPost, []scriptedResponse{status(503)}, true, 1},
+		{"远程控制指令限流不重试", http.MethodPost, []scriptedResponse{status(429)}, true, 1},
+		{"Retry-After 超过上限不重试", http.MethodGet, []scriptedResponse{{status: 429, retryAfter: "5"}}, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newScriptedServer(t, tt.script...)
+			c := NewClient(srv.URL, "", 5, nil, WithRetry(policy))
+			defer c.Close()
+
+			_, err := c.doRequest(context.Background(), tt.method, "/api/v1/cars", nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("错误 = %v, 期望出错 = %v", err, tt.wantErr)
+			}
+			if n := srv.count(); n != tt.requests {
+				t.Errorf("请求了 %d 次, 期望 %d 次", n, tt.requests)
+			}
+		})
+	}
+}
+
+func TestDoRequestRetryAfter(t *testing.T) {
+	srv := newScriptedServer(t, scriptedResponse{status: 429, retryAfter: "1"})
+	c := NewClient(srv.URL, "", 5, nil, WithRetry(RetryPolicy{MaxRetries: 1, Backoff: time.Millisecond, MaxBackoff: 2 * time.Second}))
+	defer c.Close()
+
+	start := time.Now()
+	if _, err := c.doRequest(context.Background(), http.MethodGet, "/api/v1/cars", nil); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("重试前等待了 %s, 期望按 Retry-After 等待 1s", elapsed)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var changes []bool
+	b := circuitBreaker{
+		policy:   BreakerPolicy{FailureThreshold: 2, Cooldown: time.Minute},
+		onChange: func(available bool) { changes = append(changes, available) },
+	}
+	now := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	upstream := statusError(http.StatusBadGateway, "", 0)
+	unavailable := func(err *Error) bool { return err != nil && err.Kind == KindUnavailable }
+
+	// 关闭：未达到阈值时继续放行，取消与 4xx 不计入失败
+	b.record(upstream, now)
+	b.record(requestError(context.Canceled), now)
+	b.record(statusError(http.StatusNotFound, "", 0), now)
+	b.record(upstream, now)
+	if err := b.allow(now); err != nil {
+		t.Fatalf("未达到阈值时拒绝请求: %v", err)
+	}
+
+	// 打开：连续失败达到阈值后冷却期内拒绝请求
+	b.record(upstream, now)
+	if err := b.allow(now.Add(30 * time.Second)); !unavailable(err) {
+		t.Fatalf("熔断期间 allow = %v, 期望拒绝", err)
+	}
+
+	// 半开：冷却结束后只放行一次试探请求，试探失败则重新冷却
+	probeAt := now.Add(61 * time.Second)
+	if err := b.allow(probeAt); err != nil {
+		t.Fatalf("冷却结束后未放行试探请求: %v", err)
+	}
+	if err := b.allow(probeAt); !unavailable(err) {
+		t.Fatalf("试探期间 allow = %v, 期望拒绝", err)
+	}
+	b.record(upstream, probeAt)
+	if err := b.allow(probeAt.Add(30 * time.Second)); !unavailable(err) {
+		t.Fatalf("试探失败后 allow = %v, 期望重新冷却", err)
+	}
+
+	// 试探成功后关闭
+	probeAt = probeAt.Add(61 * time.Second)
+	if err := b.allow(probeAt); err != nil {
+		t.Fatalf("冷却结束后未放行试探请求: %v", err)
+	}
+	b.record(nil, probeAt)
+	for range 3 {
+		if err := b.allow(probeAt); err != nil {
+			t.Fatalf("恢复后拒绝请求: %v", err)
+		}
+	}
+
+	if len(changes) != 2 || changes[0] || !changes[1] {
+		t.Errorf("状态变化回调 = %v, 期望 [false true]", changes)
+	}
+}
+
+func TestClientBreaker(t *testing.T) {
+	srv := newScriptedServer(t, status(502), status(502))
+	c := NewClient(srv.URL, "", 5, nil, WithBreaker(BreakerPolicy{FailureThreshold: 2, Cooldown: time.Minute}))
+	defer c.Close()
+
+	var kinds []ErrorKind
+	for i := range 3 {
+		_, err := c.doRequest(context.Background(), http.MethodGet, "/api/v1/cars/"+strconv.Itoa(i), nil)
+		var apiErr *Error
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("第 %d 次请求错误 = %v", i+1, err)
+		}
+		kinds = append(kinds, apiErr.Kind)
+	}
+	// 熔断后不再发送请求
+	if kinds[2] != KindUnavailable {
+		t.Errorf("熔断后错误类型 = %v, 期望 KindUnavailable", kinds[2])
+	}
+	if n := srv.count(); n != 2 {
+		t.Errorf("请求了 %d 次, 期望熔断后不再请求", n)
+	}
+}
@@ -1,45 +1,80 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"sync"
 	"time"
 
 	"teslamate-bot/models"
 
 	"github.com/valyala/fasthttp"
+	"golang.org/x/sync/singleflight"
 )
 
 // Client TeslaMate API客户端
 type Client struct {
 	baseURL    string
 	apiKey     string
-	carID      int
 	headers    map[string]string
+	timeout    time.Duration
 	httpClient *fasthttp.Client
+
+	// 响应缓存与并发请求合并
+	cacheMu sync.Mutex
+	cache   map[string]cacheEntry
+	flight  singleflight.Group
+
+	retry   RetryPolicy
+	breaker circuitBreaker
+
+	// 共享请求（合并后的 GET）使用的 context，Close 时取消
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // NewClient 创建新的TeslaMate API客户端
-func NewClient(baseURL, apiKey string, carID, timeout int, headers map[string]string) *Client {
-	return &Client{
+func NewClient(baseURL, apiKey string, timeout int, headers map[string]string, opts ...Option) *Client {
+	c := &Client{
 		baseURL: baseURL,
 		apiKey:  apiKey,
-		carID:   carID,
 		headers: headers,
+		timeout: time.Duration(timeout) * time.Second,
 		httpClient: &fasthttp.Client{
 			ReadTimeout:  time.Duration(timeout) * time.Second,
 			WriteTimeout: time.Duration(timeout) * time.Second,
 		},
+		cache: make(map[string]cacheEntry),
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
 
-// doRequest 执行HTTP请求
-func (c *Client) doRequest(method, path string) ([]byte, error) {
+// Close 取消后台进行中的共享请求（退出时使用），之后的 GET 请求会立即失败
+func (c *Client) Close() {
+	c.cancel()
+}
+
+// send 发送一次HTTP请求（body 为 nil 时不带请求体）
+//
+// fasthttp 不支持 context，请求在后台执行：ctx 的截止时间会缩短请求超时，
+// ctx 取消时立即返回，后台请求结束后再释放资源。
+func (c *Client) send(ctx context.Context, method, path string, body []byte) ([]byte, *Error) {
+	if err := ctx.Err(); err != nil {
+		return nil, requestError(err)
+	}
+
 	req := fasthttp.AcquireRequest()
 	resp := fasthttp.AcquireResponse()
-	defer fasthttp.ReleaseRequest(req)
-	defer fasthttp.ReleaseResponse(resp)
+	release := func() {
+		fasthttp.ReleaseRequest(req)
+		fasthttp.ReleaseResponse(resp)
+	}
 
 	// 构建完整URL
 	url := c.baseURL + path
@@ -51,6 +86,9 @@ func (c *Client) doRequest(method, path string) ([]byte, error) {
 		req.Header.Set("Authorization", "Bearer "+c.apiKey)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if body != nil {
+		req.SetBody(body)
+	}
 
 	// 设置自定义请求头
 	if c.headers != nil {
@@ -60,114 +98,203 @@ func (c *Client) doRequest(method, path string) ([]byte, error) {
 	}
 
 	// 执行请求
-	if err := c.httpClient.Do(req, resp); err != nil {
-		return nil, fmt.Errorf("请求失败: %w", err)
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- c.httpClient.DoDeadline(req, resp, deadline)
+	}()
+
+	select {
+	case <-ctx.Done():
+		go func() {
+			<-done
+			release()
+		}()
+		return nil, requestError(ctx.Err())
+	case err := <-done:
+		defer release()
+		if err != nil {
+			return nil, requestError(err)
+		}
 	}
 
 	// 检查状态码
 	statusCode := resp.StatusCode()
 	if statusCode != fasthttp.StatusOK {
-		return nil, fmt.Errorf("API返回错误状态码: %d, 响应: %s", statusCode, string(resp.Body()))
+		return nil, statusError(statusCode, string(resp.Body()), parseRetryAfter(string(resp.Header.Peek("Retry-After")), time.Now()))
 	}
 
 	// 复制响应体
-	body := make([]byte, len(resp.Body()))
-	copy(body, resp.Body())
+	respBody := make([]byte, len(resp.Body()))
+	copy(respBody, resp.Body())
 
-	return body, nil
+	return respBody, nil
+}
+
+// GetCars 获取 TeslaMate 中的全部车辆
+func (c *Client) GetCars(ctx context.Context) ([]models.Car, error) {
+	body, err := c.get(ctx, "/api/v1/cars")
+	if err != nil {
+		return nil, fmt.Errorf("获取车辆列表失败: %w", err)
+	}
+
+	var response models.CarResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, decodeError("解析车辆列表失败", err)
+	}
+
+	return response.Data.Cars, nil
 }
 
 // GetCarDetails 获取车辆详细信息
-func (c *Client) GetCarDetails() (*models.Car, error) {
-	path := fmt.Sprintf("/api/v1/cars/%d", c.carID)
-	body, err := c.doRequest("GET", path)
+func (c *Client) GetCarDetails(ctx context.Context, carID int) (*models.Car, error) {
+	path := fmt.Sprintf("/api/v1/cars/%d", carID)
+	body, err := c.get(ctx, path)
 	if err != nil {
 		return nil, fmt.Errorf("获取车辆详情失败: %w", err)
 	}
 
 	var response models.CarResponse
 	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("解析车辆详情失败: %w", err)
+		return nil, decodeError("解析车辆详情失败", err)
 	}
 
 	if len(response.Data.Cars) == 0 {
-		return nil, fmt.Errorf("未找到车辆信息")
+		return nil, notFoundError("未找到车辆信息")
 	}
 
 	return &response.Data.Cars[0], nil
 }
 
 // GetCarStatus 获取车辆当前状态
-func (c *Client) GetCarStatus() (*models.StatusResponse, error) {
-	path := fmt.Sprintf("/api/v1/cars/%d/status", c.carID)
-	body, err := c.doRequest("GET", path)
+func (c *Client) GetCarStatus(ctx context.Context, carID int) (*models.StatusResponse, error) {
+	path := fmt.Sprintf("/api/v1/cars/%d/status", carID)
+	body, err := c.get(ctx, path)
 	if err != nil {
 		return nil, fmt.Errorf("获取车辆状态失败: %w", err)
 	}
 
 	var response models.StatusResponse
 	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("解析车辆状态失败: %w", err)
+		return nil, decodeError("解析车辆状态失败", err)
 	}
 
 	return &response, nil
 }
 
 // GetBatteryHealth 获取电池健康度
-func (c *Client) GetBatteryHealth() (*models.BatteryHealthResponse, error) {
-	path := fmt.Sprintf("/api/v1/cars/%d/battery-health", c.carID)
-	body, err := c.doRequest("GET", path)
+func (c *Client) GetBatteryHealth(ctx context.Context, carID int) (*models.BatteryHealthResponse, error) {
+	path := fmt.Sprintf("/api/v1/cars/%d/battery-health", carID)
+	body, err := c.get(ctx, path)
 	if err != nil {
 		return nil, fmt.Errorf("获取电池健康度失败: %w", err)
 	}
 
 	var response models.BatteryHealthResponse
 	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("解析电池健康度失败: %w", err)
+		return nil, decodeError("解析电池健康度失败", err)
 	}
 
 	return &response, nil
 }
 
 // GetLatestCharge 获取最新充电记录
-func (c *Client) GetLatestCharge() (*models.Charge, error) {
-	path := fmt.Sprintf("/api/v1/cars/%d/charges", c.carID)
-	body, err := c.doRequest("GET", path)
+func (c *Client) GetLatestCharge(ctx context.Context, carID int) (*models.Charge, error) {
+	path := fmt.Sprintf("/api/v1/cars/%d/charges", carID)
+	body, err := c.get(ctx, path)
 	if err != nil {
 		return nil, fmt.Errorf("获取充电记录失败: %w", err)
 	}
 
 	var response models.ChargesResponse
 	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("解析充电记录失败: %w", err)
+		return nil, decodeError("解析充电记录失败", err)
 	}
 
 	if len(response.Data.Charges) == 0 {
-		return nil, fmt.Errorf("暂无充电记录")
+		return nil, notFoundError("暂无充电记录")
 	}
 
 	// 返回最新的充电记录（第一条）
 	return &response.Data.Charges[0], nil
 }
 
-// GetLatestDrive 获取最近一次驾驶记录（默认 7 天内最后一条）
-func (c *Client) GetLatestDrive() (*models.Drive, *models.Units, error) {
-	startDate := time.Now().Add(-7 * 24 * time.Hour).UTC().Format(time.RFC3339)
-	path := fmt.Sprintf("/api/v1/cars/%d/drives?startDate=%s", c.carID, url.QueryEscape(startDate))
-	body, err := c.doRequest("GET", path)
+// GetDrives 获取指定时间范围内的驾驶记录
+func (c *Client) GetDrives(ctx context.Context, carID int, start, end time.Time) ([]models.Drive, *models.Units, error) {
+	path := fmt.Sprintf("/api/v1/cars/%d/drives?%s", carID, dateRangeQuery(start, end))
+	body, err := c.get(ctx, path)
 	if err != nil {
 		return nil, nil, fmt.Errorf("获取驾驶记录失败: %w", err)
 	}
 
 	var response models.DrivesResponse
 	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, nil, fmt.Errorf("解析驾驶记录失败: %w", err)
+		return nil, nil, decodeError("解析驾驶记录失败", err)
 	}
 
-	if len(response.Data.Drives) == 0 {
-		return nil, nil, fmt.Errorf("7天内暂无驾驶记录")
+	return response.Data.Drives, &response.Data.Units, nil
+}
+
+// GetLatestDrive 获取最近一次驾驶记录（默认 7 天内最后一条）
+func (c *Client) GetLatestDrive(ctx context.Context, carID int) (*models.Drive, *models.Units, error) {
+	// 起始时间取整到小时，一小时内的查询路径相同，可以使用缓存并合并并发请求
+	start := time.Now().Add(-7 * 24 * time.Hour).Truncate(time.Hour)
+	drives, units, err := c.GetDrives(ctx, carID, start, time.Time{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(drives) == 0 {
+		return nil, nil, notFoundError("7天内暂无驾驶记录")
 	}
 
 	// API 返回按时间排序，取第一条为最近一次
-	return &response.Data.Drives[0], &response.Data.Units, nil
+	return &drives[0], units, nil
+}
+
+// GetCharges 获取指定时间范围内的充电记录
+func (c *Client) GetCharges(ctx context.Context, carID int, start, end time.Time) ([]models.Charge, error) {
+	path := fmt.Sprintf("/api/v1/cars/%d/charges?%s", carID, dateRangeQuery(start, end))
+	body, err := c.get(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("获取充电记录失败: %w", err)
+	}
+
+	var response models.ChargesResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, decodeError("解析充电记录失败", err)
+	}
+
+	return response.Data.Charges, nil
+}
+
+// dateRangeQuery 构建 startDate/endDate 查询参数（零值时间表示不限制）
+func dateRangeQuery(start, end time.Time) string {
+	query := url.Values{}
+	if !start.IsZero() {
+		query.Set("startDate", start.UTC().Format(time.RFC3339))
+	}
+	if !end.IsZero() {
+		query.Set("endDate", end.UTC().Format(time.RFC3339))
+	}
+	return query.Encode()
+}
+
+// GetUpdates 获取软件更新记录
+func (c *Client) GetUpdates(ctx context.Context, carID int) ([]models.Update, error) {
+	path := fmt.Sprintf("/api/v1/cars/%d/updates", carID)
+	body, err := c.get(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("获取更新记录失败: %w", err)
+	}
+
+	var response models.UpdatesResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, decodeError("解析更新记录失败", err)
+	}
+
+	return response.Data.Updates, nil
 }
@@ -0,0 +1,38 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"teslamate-bot/client/teslamatetest"
+)
+
+func TestGetLatestDriveCached(t *testing.T) {
+	srv := teslamatetest.NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	defer c.Close()
+
+	hour := time.Now().Truncate(time.Hour)
+	for range 2 {
+		if _, _, err := c.GetLatestDrive(context.Background(), 1); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Second)
+	}
+	if !time.Now().Add(-2 * time.Second).Truncate(time.Hour).Equal(hour) {
+		t.Skip("两次请求跨越整点，查询范围不同")
+	}
+
+	// 两次查询的时间范围相同，第二次使用缓存
+	n := 0
+	for _, r := range srv.Requests() {
+		if r == "GET /api/v1/cars/1/drives" {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("相隔一秒的两次查询请求了 %d 次, 期望 1 次", n)
+	}
+}
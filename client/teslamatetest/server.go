@@ -0,0 +1,136 @@
+// Package teslamatetest 提供用于测试的 TeslaMateApi 模拟服务器
+//
+// 默认路由使用 fixtures 目录中的 JSON：车辆 1 为数据完整的车辆，
+// 车辆 2 用于边界情况（字段为 null、记录为空）。
+package teslamatetest
+
+import (
+	"embed"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"teslamate-bot/client"
+)
+
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+// defaultRoutes 默认路由及对应的 fixture
+var defaultRoutes = map[string]string{
+	"/api/v1/cars":                  "cars.json",
+	"/api/v1/cars/1":                "car.json",
+	"/api/v1/cars/1/status":         "status.json",
+	"/api/v1/cars/1/battery-health": "battery_health.json",
+	"/api/v1/cars/1/charges":        "charges.json",
+	"/api/v1/cars/1/drives":         "drives.json",
+	"/api/v1/cars/1/updates":        "updates.json",
+	"/api/v1/cars/2":                "car_nulls.json",
+	"/api/v1/cars/2/status":         "status_nulls.json",
+	"/api/v1/cars/2/battery-health": "battery_health_nulls.json",
+	"/api/v1/cars/2/charges":        "charges_empty.json",
+	"/api/v1/cars/2/drives":         "drives_empty.json",
+	"/api/v1/cars/2/updates":        "updates_empty.json",
+}
+
+// commands 默认支持的远程指令
+var commands = []client.Command{
+	client.CommandDoorLock,
+	client.CommandDoorUnlock,
+	client.CommandClimateStart,
+	client.CommandClimateStop,
+	client.CommandChargeStart,
+	client.CommandChargeStop,
+	client.CommandChargePortDoorOpen,
+	client.CommandChargePortDoorClose,
+	client.CommandSetChargeLimit,
+	client.CommandSetSentryMode,
+	client.CommandHonkHorn,
+	client.CommandFlashLights,
+}
+
+// response 一条路由的响应
+type response struct {
+	status int
+	body   []byte
+}
+
+// Server TeslaMateApi 模拟服务器
+//
+// 路由按路径匹配（忽略查询参数），未配置的路径返回 404。
+// 车辆 1、2 的远程指令默认执行成功。
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	routes   map[string]response
+	requests []string
+}
+
+// NewServer 启动使用默认 fixture 的模拟服务器，使用完毕后需调用 Close
+func NewServer() *Server {
+	s := &Server{routes: make(map[string]response)}
+	for path, name := range defaultRoutes {
+		s.routes[path] = response{status: http.StatusOK, body: Fixture(name)}
+	}
+	ok := Fixture("command_ok.json")
+	for _, carID := range []int{1, 2} {
+		s.routes[fmt.Sprintf("/api/v1/cars/%d/wake_up", carID)] = response{status: http.StatusOK, body: Fixture(defaultRoutes[fmt.Sprintf("/api/v1/cars/%d", carID)])}
+		for _, command := range commands {
+			s.routes[fmt.Sprintf("/api/v1/cars/%d/command/%s", carID, command)] = response{status: http.StatusOK, body: ok}
+		}
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Fixture 读取 fixture 内容（不存在时 panic）
+func Fixture(name string) []byte {
+	data, err := fixtures.ReadFile("fixtures/" + name)
+	if err != nil {
+		panic(fmt.Sprintf("teslamatetest: fixture %s 不存在", name))
+	}
+	return data
+}
+
+// Handle 设置路径的响应（覆盖默认路由）
+func (s *Server) Handle(path string, status int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[path] = response{status: status, body: []byte(body)}
+}
+
+// HandleFixture 设置路径返回指定的 fixture
+func (s *Server) HandleFixture(path, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[path] = response{status: http.StatusOK, body: Fixture(name)}
+}
+
+// Requests 已收到的请求（"方法 路径"，不含查询参数）
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// Client 创建连接到该服务器的客户端（不重试、不熔断）
+func (s *Server) Client() *client.Client {
+	return client.NewClient(s.URL, "test-api-key", 5, nil)
+}
+
+// serve 按路由返回响应
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	resp, ok := s.routes[r.URL.Path]
+	s.mu.Unlock()
+
+	if !ok {
+		resp = response{status: http.StatusNotFound, body: []byte(`{"error":"not found"}`)}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(resp.status)
+	w.Write(resp.body)
+}
@@ -1,14 +1,20 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"teslamate-bot/bot"
 	"teslamate-bot/client"
 	"teslamate-bot/config"
+	"teslamate-bot/store"
 )
 
 var (
@@ -69,28 +75,52 @@ func main() {
 	tmClient := client.NewClient(
 		cfg.TeslaMate.APIURL,
 		cfg.TeslaMate.APIKey,
-		cfg.TeslaMate.CarID,
 		cfg.TeslaMate.Timeout,
 		cfg.TeslaMate.Headers,
+		client.WithRetry(client.RetryPolicy{
+			MaxRetries: cfg.TeslaMate.Retry.MaxRetries,
+			Backoff:    time.Duration(cfg.TeslaMate.Retry.BackoffMillis) * time.Millisecond,
+			MaxBackoff: time.Duration(cfg.TeslaMate.Retry.MaxBackoffSeconds) * time.Second,
+		}),
+		client.WithBreaker(client.BreakerPolicy{
+			FailureThreshold: cfg.TeslaMate.Breaker.FailureThreshold,
+			Cooldown:         time.Duration(cfg.TeslaMate.Breaker.CooldownSeconds) * time.Second,
+		}),
 	)
-	log.Printf("TeslaMate API客户端初始化完成 (CarID: %d)", cfg.TeslaMate.CarID)
+	log.Println("TeslaMate API客户端初始化完成")
+
+	// 打开本地状态存储
+	st, err := store.Open(cfg.Storage.Path)
+	if err != nil {
+		log.Fatalf("打开状态存储失败: %v", err)
+	}
+	log.Printf("状态存储: %s（数据库版本 %d）", cfg.Storage.Path, store.SchemaVersion)
+
+	// 收到退出信号时取消 ctx，Bot 停止接收更新并等待处理中的任务完成
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	// 初始化Telegram Bot
-	tgBot, err := bot.NewBot(
-		cfg.Telegram.BotToken,
-		cfg.Telegram.WhitelistChatIDs,
-		cfg.Telegram.APIEndpoint,
-		tmClient,
-	)
+	tgBot, err := bot.NewBot(ctx, cfg, tmClient, st)
 	if err != nil {
 		log.Fatalf("初始化Telegram Bot失败: %v", err)
 	}
 
-	log.Printf("已授权 %d 个会话使用Bot", len(cfg.Telegram.WhitelistChatIDs))
+	log.Printf("已授权 %d 个会话、%d 个用户使用Bot", len(cfg.Telegram.WhitelistChatIDs)+len(cfg.Telegram.Chats), len(cfg.Telegram.Users))
 
 	// 启动Bot
 	log.Println("Tesla Telegram Bot 启动成功!")
-	if err := tgBot.Start(); err != nil {
+	if err := tgBot.Start(ctx); err != nil {
+		if errors.Is(err, bot.ErrShutdownTimeout) {
+			// 未结束的任务可能仍在写入，不关闭状态存储（已提交的事务均已落盘）
+			log.Fatalf("Bot 未能正常停止: %v", err)
+		}
 		log.Fatalf("Bot运行错误: %v", err)
 	}
+
+	// 处理中的任务均已完成，关闭状态存储
+	if err := st.Close(); err != nil {
+		log.Printf("保存状态失败: %v", err)
+	}
+	log.Println("Bot 已停止")
 }
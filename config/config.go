@@ -1,8 +1,13 @@
 package config
 
 import (
+	"encoding/base32"
 	"fmt"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 )
@@ -11,22 +16,182 @@ import (
 type Config struct {
 	Telegram  TelegramConfig  `toml:"telegram"`
 	TeslaMate TeslaMateConfig `toml:"teslamate"`
+	Monitor   MonitorConfig   `toml:"monitor"`
+	Storage   StorageConfig   `toml:"storage"`
+	Scheduler SchedulerConfig `toml:"scheduler"`
+	Auth      AuthConfig      `toml:"auth"`
 }
 
 // TelegramConfig Telegram Bot配置
 type TelegramConfig struct {
-	BotToken         string  `toml:"bot_token"`
-	WhitelistChatIDs []int64 `toml:"whitelist_chat_ids"`
-	APIEndpoint      string  `toml:"api_endpoint"` // 自定义API端点（可选）
+	BotToken         string         `toml:"bot_token"`
+	WhitelistChatIDs []int64        `toml:"whitelist_chat_ids"`
+	APIEndpoint      string         `toml:"api_endpoint"` // 自定义API端点（可选）
+	DefaultCars      map[string]int `toml:"default_cars"` // 各会话的默认车辆（可选，键为会话ID）
+	DefaultRole      string         `toml:"default_role"` // 白名单会话的默认角色（可选，默认 viewer）
+	Mode             string         `toml:"mode"`         // 接收更新的方式: polling（默认）/ webhook
+	Webhook          WebhookConfig  `toml:"webhook"`
+	Workers          int            `toml:"workers"` // 并发处理更新的数量（可选，默认 4，同一会话按顺序处理）
+	Users            []UserRole     `toml:"users"`   // 按用户分配角色（可选，优先于会话角色）
+	Chats            []ChatRole     `toml:"chats"`   // 按会话分配角色（可选，会话自动加入白名单）
+
+	DefaultCarIDs map[int64]int `toml:"-"` // 由 DefaultCars 解析得到
+}
+
+// WebhookConfig Webhook 模式配置
+type WebhookConfig struct {
+	URL         string `toml:"url"`          // Telegram 推送更新的公网地址（https）
+	Listen      string `toml:"listen"`       // 本地监听地址
+	Path        string `toml:"path"`         // 本地监听路径（可选，默认与 url 的路径相同）
+	SecretToken string `toml:"secret_token"` // 校验 X-Telegram-Bot-Api-Secret-Token 请求头（必填）
+	CertFile    string `toml:"cert_file"`    // TLS 证书（可选，由反向代理终止 TLS 时留空）
+	KeyFile     string `toml:"key_file"`     // TLS 私钥（可选）
+}
+
+// 角色名称（权限依次递增）
+const (
+	RoleViewer     = "viewer"     // 只能查看
+	RoleController = "controller" // 可远程控制车辆、修改推送与提醒设置
+	RoleAdmin      = "admin"      // 可管理用户
+)
+
+// UserRole 用户角色
+type UserRole struct {
+	UserID int64  `toml:"user_id"`
+	Role   string `toml:"role"`
+}
+
+// ChatRole 会话角色
+type ChatRole struct {
+	ChatID int64  `toml:"chat_id"`
+	Role   string `toml:"role"`
 }
 
 // TeslaMateConfig TeslaMate API配置
 type TeslaMateConfig struct {
 	APIURL  string            `toml:"api_url"`
 	APIKey  string            `toml:"api_key"`
-	CarID   int               `toml:"car_id"`
+	CarID   int               `toml:"car_id"`  // 默认车辆（可选，为0时使用第一辆车）
+	CarIDs  []int             `toml:"car_ids"` // 后台监控的车辆（可选，留空监控全部车辆）
 	Timeout int               `toml:"timeout"`
 	Headers map[string]string `toml:"headers"` // 自定义请求头（可选）
+	Retry   RetryConfig       `toml:"retry"`
+	Breaker BreakerConfig     `toml:"circuit_breaker"`
+}
+
+// maxRetries 查询请求最多重试的次数
+const maxRetries = 10
+
+// RetryConfig 请求重试配置（仅重试查询请求，远程控制指令不重试）
+type RetryConfig struct {
+	MaxRetries        int `toml:"max_retries"`         // 最大重试次数（默认 2，最多 10，设为 -1 关闭重试）
+	BackoffMillis     int `toml:"backoff_ms"`          // 首次重试前的等待时间（毫秒），之后每次翻倍
+	MaxBackoffSeconds int `toml:"max_backoff_seconds"` // 单次等待时间上限（秒），同时限制 Retry-After
+}
+
+// BreakerConfig 熔断配置
+type BreakerConfig struct {
+	FailureThreshold int `toml:"failure_threshold"` // 连续失败该次数后熔断（默认 5，设为 -1 关闭熔断）
+	CooldownSeconds  int `toml:"cooldown_seconds"`  // 熔断后等待多久再尝试请求（秒）
+}
+
+// MonitorConfig 后台监控配置
+type MonitorConfig struct {
+	Enabled   bool                `toml:"enabled"`
+	Interval  int                 `toml:"interval"` // 轮询间隔（秒）
+	Security  SecurityWatchConfig `toml:"security"`
+	Tires     TiresWatchConfig    `toml:"tires"`
+	Drain     DrainWatchConfig    `toml:"drain"`
+	State     StateWatchConfig    `toml:"state"`
+	Geofences []GeofenceConfig    `toml:"geofences"` // Bot本地地理围栏（TeslaMate 中未配置的地点）
+}
+
+// SecurityWatchConfig 停车安全监控配置
+type SecurityWatchConfig struct {
+	GraceMinutes      int `toml:"grace_minutes"`       // 停车后未锁/未关的宽限期（分钟）
+	RealertMinutes    int `toml:"realert_minutes"`     // 首次再提醒间隔（分钟），之后逐次翻倍
+	MaxRealertMinutes int `toml:"max_realert_minutes"` // 再提醒间隔上限（分钟）
+}
+
+// TiresWatchConfig 胎压监控配置（单位与 TeslaMate 设置的胎压单位一致，为0时不检查）
+type TiresWatchConfig struct {
+	MinPressure float64 `toml:"min_pressure"` // 最低胎压
+	MaxDelta    float64 `toml:"max_delta"`    // 四轮胎压最大差值
+}
+
+// DrainWatchConfig 停车掉电监控配置
+type DrainWatchConfig struct {
+	ReportTime string `toml:"report_time"` // 早间报告推送时间（HH:MM），留空不推送
+}
+
+// StateWatchConfig 车辆状态监控配置
+type StateWatchConfig struct {
+	OfflineAlertMinutes int `toml:"offline_alert_minutes"` // 离线（非休眠）超过该时长告警（分钟）
+}
+
+// GeofenceConfig 本地地理围栏
+type GeofenceConfig struct {
+	Name      string  `toml:"name"`
+	Latitude  float64 `toml:"latitude"`
+	Longitude float64 `toml:"longitude"`
+	Radius    float64 `toml:"radius"` // 半径（米）
+}
+
+// SchedulerConfig 定时任务配置
+type SchedulerConfig struct {
+	Timezone string         `toml:"timezone"` // 时区（如 Asia/Shanghai），留空使用 TZ 环境变量
+	Digests  []DigestConfig `toml:"digests"`
+}
+
+// Location 定时任务使用的时区（未配置时使用本地时区）
+func (c SchedulerConfig) Location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("加载时区 %s 失败: %w", c.Timezone, err)
+	}
+	return loc, nil
+}
+
+// DigestConfig 定时摘要配置
+type DigestConfig struct {
+	Name     string  `toml:"name"`
+	Schedule string  `toml:"schedule"` // cron 表达式（分 时 日 月 周）
+	Period   string  `toml:"period"`   // 统计周期: day / week
+	ChatIDs  []int64 `toml:"chat_ids"` // 接收会话，留空则发送给所有订阅了摘要的白名单会话
+}
+
+// PeriodDuration 统计周期时长
+func (d DigestConfig) PeriodDuration() time.Duration {
+	if d.Period == "week" {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// AuthConfig 敏感操作二次验证配置（未配置 PIN/TOTP 的用户不需要验证）
+type AuthConfig struct {
+	SessionMinutes int        `toml:"session_minutes"` // 验证通过后的有效时长（分钟）
+	MaxFailures    int        `toml:"max_failures"`    // 连续失败该次数后锁定
+	LockoutMinutes int        `toml:"lockout_minutes"` // 锁定时长（分钟）
+	Sensitive      []string   `toml:"sensitive"`       // 需要验证的功能（命令名）
+	Users          []AuthUser `toml:"users"`
+}
+
+// AuthUser 用户的二次验证方式（PIN 与 TOTP 任选其一或同时配置）
+type AuthUser struct {
+	UserID     int64  `toml:"user_id"`
+	PIN        string `toml:"pin"`         // 数字 PIN（至少4位）
+	TOTPSecret string `toml:"totp_secret"` // TOTP 密钥（Base32，兼容常见验证器应用）
+}
+
+// StorageConfig 本地状态存储配置
+type StorageConfig struct {
+	Path                  string `toml:"path"`                    // 数据库文件路径
+	SnapshotMinutes       int    `toml:"snapshot_minutes"`        // 车辆状态快照间隔（分钟，默认 15，设为 -1 关闭）
+	SnapshotRetentionDays int    `toml:"snapshot_retention_days"` // 快照保留天数（默认 90）
 }
 
 // LoadConfig 从文件加载配置
@@ -57,19 +222,204 @@ func (c *Config) Validate() error {
 	if c.Telegram.BotToken == "" {
 		return fmt.Errorf("telegram.bot_token 不能为空")
 	}
-	if len(c.Telegram.WhitelistChatIDs) == 0 {
-		return fmt.Errorf("telegram.whitelist_chat_ids 不能为空")
+	if len(c.Telegram.WhitelistChatIDs) == 0 && len(c.Telegram.Chats) == 0 && len(c.Telegram.Users) == 0 {
+		return fmt.Errorf("telegram.whitelist_chat_ids、telegram.chats 与 telegram.users 不能同时为空")
+	}
+	switch c.Telegram.Mode {
+	case "":
+		c.Telegram.Mode = "polling"
+	case "polling":
+	case "webhook":
+		if err := c.Telegram.Webhook.validate(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("telegram.mode 只能为 polling 或 webhook")
+	}
+	if c.Telegram.Workers <= 0 {
+		c.Telegram.Workers = 4
+	}
+	if c.Telegram.DefaultRole == "" {
+		c.Telegram.DefaultRole = RoleViewer
+	}
+	if !validRole(c.Telegram.DefaultRole) {
+		return fmt.Errorf("telegram.default_role 无效: %s（可选 viewer、controller、admin）", c.Telegram.DefaultRole)
+	}
+	for _, u := range c.Telegram.Users {
+		if !validRole(u.Role) {
+			return fmt.Errorf("用户 %d 的角色无效: %s（可选 viewer、controller、admin）", u.UserID, u.Role)
+		}
+	}
+	for _, chat := range c.Telegram.Chats {
+		if !validRole(chat.Role) {
+			return fmt.Errorf("会话 %d 的角色无效: %s（可选 viewer、controller、admin）", chat.ChatID, chat.Role)
+		}
 	}
 	// api_endpoint为可选项，如果为空则使用默认Telegram API
 	if c.TeslaMate.APIURL == "" {
 		return fmt.Errorf("teslamate.api_url 不能为空")
 	}
 	// api_key 可选
-	if c.TeslaMate.CarID <= 0 {
-		return fmt.Errorf("teslamate.car_id 必须大于0")
+	if c.TeslaMate.CarID < 0 {
+		return fmt.Errorf("teslamate.car_id 不能小于0")
+	}
+	c.Telegram.DefaultCarIDs = make(map[int64]int, len(c.Telegram.DefaultCars))
+	for key, carID := range c.Telegram.DefaultCars {
+		chatID, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return fmt.Errorf("telegram.default_cars 的键必须为会话ID: %s", key)
+		}
+		c.Telegram.DefaultCarIDs[chatID] = carID
 	}
 	if c.TeslaMate.Timeout <= 0 {
 		c.TeslaMate.Timeout = 30 // 默认30秒
 	}
+	if c.TeslaMate.Retry.MaxRetries == 0 {
+		c.TeslaMate.Retry.MaxRetries = 2
+	}
+	if c.TeslaMate.Retry.MaxRetries > maxRetries {
+		return fmt.Errorf("teslamate.retry.max_retries 不能超过 %d", maxRetries)
+	}
+	if c.TeslaMate.Retry.BackoffMillis <= 0 {
+		c.TeslaMate.Retry.BackoffMillis = 500
+	}
+	if c.TeslaMate.Retry.MaxBackoffSeconds <= 0 {
+		c.TeslaMate.Retry.MaxBackoffSeconds = 10
+	}
+	if c.TeslaMate.Breaker.FailureThreshold == 0 {
+		c.TeslaMate.Breaker.FailureThreshold = 5
+	}
+	if c.TeslaMate.Breaker.CooldownSeconds <= 0 {
+		c.TeslaMate.Breaker.CooldownSeconds = 30
+	}
+	if c.Monitor.Interval <= 0 {
+		c.Monitor.Interval = 60 // 默认60秒
+	}
+	if c.Monitor.Security.GraceMinutes <= 0 {
+		c.Monitor.Security.GraceMinutes = 10
+	}
+	if c.Monitor.Security.RealertMinutes <= 0 {
+		c.Monitor.Security.RealertMinutes = 30
+	}
+	if c.Monitor.Security.MaxRealertMinutes < c.Monitor.Security.RealertMinutes {
+		c.Monitor.Security.MaxRealertMinutes = max(240, c.Monitor.Security.RealertMinutes)
+	}
+	if c.Monitor.Drain.ReportTime != "" {
+		if _, err := time.Parse("15:04", c.Monitor.Drain.ReportTime); err != nil {
+			return fmt.Errorf("monitor.drain.report_time 格式错误（应为 HH:MM）: %w", err)
+		}
+	}
+	if c.Monitor.State.OfflineAlertMinutes <= 0 {
+		c.Monitor.State.OfflineAlertMinutes = 30
+	}
+	for i := range c.Monitor.Geofences {
+		fence := &c.Monitor.Geofences[i]
+		if fence.Name == "" {
+			return fmt.Errorf("monitor.geofences[%d].name 不能为空", i)
+		}
+		if fence.Radius <= 0 {
+			fence.Radius = 100 // 默认100米
+		}
+	}
+	for i := range c.Scheduler.Digests {
+		digest := &c.Scheduler.Digests[i]
+		if digest.Schedule == "" {
+			return fmt.Errorf("scheduler.digests[%d].schedule 不能为空", i)
+		}
+		switch digest.Period {
+		case "":
+			digest.Period = "day"
+		case "day", "week":
+		default:
+			return fmt.Errorf("scheduler.digests[%d].period 只能为 day 或 week", i)
+		}
+		if digest.Name == "" {
+			digest.Name = "每日摘要"
+			if digest.Period == "week" {
+				digest.Name = "每周摘要"
+			}
+		}
+	}
+	if c.Storage.Path == "" {
+		c.Storage.Path = "data/teslamate-bot.db"
+	}
+	if c.Storage.SnapshotMinutes == 0 {
+		c.Storage.SnapshotMinutes = 15
+	}
+	if c.Storage.SnapshotRetentionDays <= 0 {
+		c.Storage.SnapshotRetentionDays = 90
+	}
+	if c.Auth.SessionMinutes <= 0 {
+		c.Auth.SessionMinutes = 15
+	}
+	if c.Auth.MaxFailures <= 0 {
+		c.Auth.MaxFailures = 5
+	}
+	if c.Auth.LockoutMinutes <= 0 {
+		c.Auth.LockoutMinutes = 15
+	}
+	if c.Auth.Sensitive == nil {
+		c.Auth.Sensitive = []string{"location", "info", "drive", "control"}
+	}
+	for i, u := range c.Auth.Users {
+		if u.PIN == "" && u.TOTPSecret == "" {
+			return fmt.Errorf("auth.users[%d] 需要配置 pin 或 totp_secret", i)
+		}
+		if u.PIN != "" {
+			if _, err := strconv.Atoi(u.PIN); err != nil || len(u.PIN) < 4 {
+				return fmt.Errorf("auth.users[%d].pin 必须为至少4位数字", i)
+			}
+		}
+		if u.TOTPSecret != "" {
+			if _, err := DecodeTOTPSecret(u.TOTPSecret); err != nil {
+				return fmt.Errorf("auth.users[%d].totp_secret 不是有效的 Base32: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// DecodeTOTPSecret 解码 Base32 格式的 TOTP 密钥（忽略大小写、空格和填充）
+func DecodeTOTPSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	secret = strings.TrimRight(secret, "=")
+	return base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+}
+
+// validate 校验 Webhook 配置并填充默认值
+func (w *WebhookConfig) validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("telegram.webhook.url 必须为 https 地址: %s", w.URL)
+	}
+	if w.Listen == "" {
+		w.Listen = ":8443"
+	}
+	if w.Path == "" {
+		w.Path = u.Path
+	}
+	if !strings.HasPrefix(w.Path, "/") {
+		w.Path = "/" + w.Path
+	}
+	if (w.CertFile == "") != (w.KeyFile == "") {
+		return fmt.Errorf("telegram.webhook.cert_file 与 key_file 需同时配置")
+	}
+	if w.SecretToken == "" {
+		return fmt.Errorf("telegram.webhook.secret_token 未配置（用于校验请求来自 Telegram）")
+	}
+	if len(w.SecretToken) > 256 || strings.ContainsFunc(w.SecretToken, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-')
+	}) {
+		return fmt.Errorf("telegram.webhook.secret_token 只能包含字母、数字、_ 和 -，且不超过256个字符")
+	}
 	return nil
 }
+
+// validRole 判断角色名称是否有效
+func validRole(role string) bool {
+	switch role {
+	case RoleViewer, RoleController, RoleAdmin:
+		return true
+	}
+	return false
+}
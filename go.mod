@@ -5,11 +5,15 @@ go 1.25.1
 require (
 	github.com/BurntSushi/toml v1.6.0
 	github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.5.1
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/valyala/fasthttp v1.69.0
+	go.etcd.io/bbolt v1.4.3
+	golang.org/x/sync v0.10.0
 )
 
 require (
 	github.com/andybalholm/brotli v1.2.0 // indirect
 	github.com/klauspost/compress v1.18.2 // indirect
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	golang.org/x/sys v0.39.0 // indirect
 )
@@ -349,3 +349,27 @@ type DriveRange struct {
 	EndRange   float64 `json:"end_range"`
 	RangeDiff  float64 `json:"range_diff"`
 }
+
+// UpdatesResponse 软件更新记录响应
+type UpdatesResponse struct {
+	Data struct {
+		Car     StatusCar `json:"car"`
+		Updates []Update  `json:"updates"`
+	} `json:"data"`
+}
+
+// Update 软件更新记录
+type Update struct {
+	UpdateID  int    `json:"update_id"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	Version   string `json:"version"`
+}
+
+// CommandResponse 车辆远程指令响应
+type CommandResponse struct {
+	Response struct {
+		Result bool   `json:"result"`
+		Reason string `json:"reason"`
+	} `json:"response"`
+}
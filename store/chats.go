@@ -0,0 +1,69 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ChatPrefs 会话偏好
+type ChatPrefs struct {
+	Notify    map[string]bool `json:"notify,omitempty"`     // 推送主题开关（未设置的主题使用默认值）
+	ActiveCar int             `json:"active_car,omitempty"` // 通过 /cars 选择的车辆，0 表示未选择
+}
+
+// chatKey 会话在 chats 存储桶中的键
+func chatKey(chatID int64) []byte {
+	return []byte(strconv.FormatInt(chatID, 10))
+}
+
+// ChatPrefs 读取会话偏好，未保存过时返回 false
+func (s *Store) ChatPrefs(chatID int64) (ChatPrefs, bool, error) {
+	var prefs ChatPrefs
+	var found bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		found, err = getJSON(tx.Bucket(chatsBucket), chatKey(chatID), &prefs)
+		return err
+	})
+	if err != nil {
+		return ChatPrefs{}, false, fmt.Errorf("读取会话 %d 偏好失败: %w", chatID, err)
+	}
+	return prefs, found, nil
+}
+
+// AllChatPrefs 读取全部会话的偏好
+func (s *Store) AllChatPrefs() (map[int64]ChatPrefs, error) {
+	all := make(map[int64]ChatPrefs)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(chatsBucket).ForEach(func(k, v []byte) error {
+			chatID, err := strconv.ParseInt(string(k), 10, 64)
+			if err != nil {
+				return fmt.Errorf("无效的会话ID %q", k)
+			}
+			var prefs ChatPrefs
+			if err := json.Unmarshal(v, &prefs); err != nil {
+				return fmt.Errorf("解析会话 %d 偏好失败: %w", chatID, err)
+			}
+			all[chatID] = prefs
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取会话偏好失败: %w", err)
+	}
+	return all, nil
+}
+
+// PutChatPrefs 保存会话偏好
+func (s *Store) PutChatPrefs(chatID int64, prefs ChatPrefs) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(chatsBucket), chatKey(chatID), prefs)
+	})
+	if err != nil {
+		return fmt.Errorf("保存会话 %d 偏好失败: %w", chatID, err)
+	}
+	return nil
+}
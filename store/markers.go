@@ -0,0 +1,40 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Marker 最后处理的事件，用于重启后跳过已推送过的事件
+type Marker struct {
+	ID int64     `json:"id"` // 事件ID（如行程ID），0 表示尚无事件
+	At time.Time `json:"at"` // 记录时间
+}
+
+// Marker 读取指定名称（如 drive:1）的事件标记，未记录过时返回 false
+func (s *Store) Marker(name string) (Marker, bool, error) {
+	var m Marker
+	var found bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		found, err = getJSON(tx.Bucket(markersBucket), []byte(name), &m)
+		return err
+	})
+	if err != nil {
+		return Marker{}, false, fmt.Errorf("读取事件标记 %s 失败: %w", name, err)
+	}
+	return m, found, nil
+}
+
+// SetMarker 记录最后处理的事件
+func (s *Store) SetMarker(name string, m Marker) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(markersBucket), []byte(name), m)
+	})
+	if err != nil {
+		return fmt.Errorf("保存事件标记 %s 失败: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// schemaVersionKey 数据库版本在 meta 存储桶中的键
+var schemaVersionKey = []byte("schema_version")
+
+// migration 数据库升级步骤，每个步骤在独立的事务中执行并同时更新数据库版本
+type migration struct {
+	name string
+	run  func(tx *bolt.Tx) error
+}
+
+// migrations 按顺序执行的升级步骤，第 i 个步骤将数据库升级到版本 i+1
+//
+// 已发布的步骤不能修改或删除，调整数据结构时在末尾追加新步骤。
+var migrations = []migration{
+	{"创建存储桶", createBuckets},
+}
+
+// SchemaVersion 当前程序使用的数据库版本
+var SchemaVersion = len(migrations)
+
+// migrate 将数据库升级到当前版本
+func (s *Store) migrate() error {
+	var version int
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		version, err = schemaVersion(tx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if version > SchemaVersion {
+		return fmt.Errorf("数据库版本 %d 高于当前程序支持的版本 %d，请升级程序", version, SchemaVersion)
+	}
+
+	for v := version; v < SchemaVersion; v++ {
+		m := migrations[v]
+		err := s.db.Update(func(tx *bolt.Tx) error {
+			if err := m.run(tx); err != nil {
+				return err
+			}
+			return tx.Bucket(metaBucket).Put(schemaVersionKey, []byte(strconv.Itoa(v+1)))
+		})
+		if err != nil {
+			return fmt.Errorf("升级数据库到版本 %d（%s）失败: %w", v+1, m.name, err)
+		}
+		if version > 0 {
+			log.Printf("数据库已升级到版本 %d: %s", v+1, m.name)
+		}
+	}
+	return nil
+}
+
+// schemaVersion 读取数据库版本，新数据库为 0
+func schemaVersion(tx *bolt.Tx) (int, error) {
+	meta := tx.Bucket(metaBucket)
+	if meta == nil {
+		return 0, nil
+	}
+	raw := meta.Get(schemaVersionKey)
+	if raw == nil {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(string(raw))
+	if err != nil {
+		return 0, fmt.Errorf("无效的数据库版本 %q", raw)
+	}
+	return version, nil
+}
+
+// createBuckets 版本 1: 创建存储桶
+func createBuckets(tx *bolt.Tx) error {
+	for _, name := range [][]byte{metaBucket, stateBucket, chatsBucket, markersBucket, snapshotsBucket} {
+		if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
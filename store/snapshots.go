@@ -0,0 +1,113 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// snapshotKey 快照的键（UnixNano 大端序，按字节排序即按时间排序，1970 年之前的时间视为 0）
+func snapshotKey(at time.Time) []byte {
+	key := make([]byte, 8)
+	if at.After(time.Unix(0, 0)) {
+		binary.BigEndian.PutUint64(key, uint64(at.UnixNano()))
+	}
+	return key
+}
+
+// snapshotTime 从快照的键还原时间
+func snapshotTime(key []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(key)))
+}
+
+// carBucket 车辆快照存储桶的名称
+func carBucket(carID int) []byte {
+	return []byte(strconv.Itoa(carID))
+}
+
+// PutSnapshot 保存车辆在指定时间的状态快照
+func (s *Store) PutSnapshot(carID int, at time.Time, v any) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(snapshotsBucket).CreateBucketIfNotExists(carBucket(carID))
+		if err != nil {
+			return err
+		}
+		return putJSON(b, snapshotKey(at), v)
+	})
+	if err != nil {
+		return fmt.Errorf("保存车辆 %d 快照失败: %w", carID, err)
+	}
+	return nil
+}
+
+// LatestSnapshot 读取车辆最新的快照，没有快照时返回 false
+func (s *Store) LatestSnapshot(carID int, v any) (time.Time, bool, error) {
+	var at time.Time
+	var found bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(snapshotsBucket).Bucket(carBucket(carID))
+		if b == nil {
+			return nil
+		}
+		k, raw := b.Cursor().Last()
+		if k == nil {
+			return nil
+		}
+		at, found = snapshotTime(k), true
+		return json.Unmarshal(raw, v)
+	})
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("读取车辆 %d 快照失败: %w", carID, err)
+	}
+	return at, found, nil
+}
+
+// Snapshots 按时间顺序遍历车辆在 since 之后（含）的快照（since 为零值时遍历全部），fn 返回错误时停止
+//
+// raw 仅在 fn 执行期间有效，需要保留时请先解析或复制。
+func (s *Store) Snapshots(carID int, since time.Time, fn func(at time.Time, raw json.RawMessage) error) error {
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(snapshotsBucket).Bucket(carBucket(carID))
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		for k, raw := c.Seek(snapshotKey(since)); k != nil; k, raw = c.Next() {
+			if err := fn(snapshotTime(k), raw); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("读取车辆 %d 快照失败: %w", carID, err)
+	}
+	return nil
+}
+
+// PruneSnapshots 删除所有车辆在 before 之前的快照，返回删除的数量
+func (s *Store) PruneSnapshots(before time.Time) (int, error) {
+	var pruned int
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(snapshotsBucket).ForEachBucket(func(name []byte) error {
+			c := tx.Bucket(snapshotsBucket).Bucket(name).Cursor()
+			end := snapshotKey(before)
+			for k, _ := c.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = c.First() {
+				if err := c.Delete(); err != nil {
+					return err
+				}
+				pruned++
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("清理车辆快照失败: %w", err)
+	}
+	return pruned, nil
+}
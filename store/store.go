@@ -0,0 +1,117 @@
+// Package store 基于 bbolt 的本地数据存储（用于在重启后保留Bot运行状态）
+//
+// 数据按用途分为以下存储桶：
+//   - meta: 数据库版本
+//   - state: 各模块的运行状态（键值形式，值为 JSON）
+//   - chats: 会话偏好
+//   - markers: 最后处理的事件
+//   - snapshots: 车辆状态快照（按车辆分桶，按时间排序）
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// openTimeout 等待数据库文件锁的最长时间（另一个进程正在使用时返回错误）
+const openTimeout = 3 * time.Second
+
+var (
+	metaBucket      = []byte("meta")
+	stateBucket     = []byte("state")
+	chatsBucket     = []byte("chats")
+	markersBucket   = []byte("markers")
+	snapshotsBucket = []byte("snapshots")
+)
+
+// Store 本地数据存储
+type Store struct {
+	db *bolt.DB
+}
+
+// Open 打开（或创建）数据库，并升级到当前数据库版本
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("创建数据目录失败: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		if errors.Is(err, bolt.ErrTimeout) {
+			return nil, fmt.Errorf("打开数据库失败: %s 正被其他进程使用", path)
+		}
+		return nil, fmt.Errorf("打开数据库失败: %w", err)
+	}
+
+	s := &Store{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get 读取指定键的值，键不存在时返回 false
+func (s *Store) Get(key string, v any) (bool, error) {
+	var found bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(stateBucket).Get([]byte(key))
+		if raw == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(raw, v)
+	})
+	if err != nil {
+		return false, fmt.Errorf("读取状态 %s 失败: %w", key, err)
+	}
+	return found, nil
+}
+
+// Put 写入指定键的值（事务提交后即已落盘）
+func (s *Store) Put(key string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("序列化状态 %s 失败: %w", key, err)
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(stateBucket).Put([]byte(key), raw)
+	})
+	if err != nil {
+		return fmt.Errorf("写入状态 %s 失败: %w", key, err)
+	}
+	return nil
+}
+
+// Close 关闭数据库，之后的读写都会返回错误
+func (s *Store) Close() error {
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("关闭数据库失败: %w", err)
+	}
+	return nil
+}
+
+// getJSON 读取存储桶中的 JSON 值，键不存在时返回 false
+func getJSON(b *bolt.Bucket, key []byte, v any) (bool, error) {
+	raw := b.Get(key)
+	if raw == nil {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, v)
+}
+
+// putJSON 将值序列化为 JSON 写入存储桶
+func putJSON(b *bolt.Bucket, key []byte, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, raw)
+}
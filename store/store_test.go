@@ -0,0 +1,133 @@
+package store
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestReopenKeepsData(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "teslamate-bot.db")
+	st, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Put("alerts", map[string]int{"100": 20}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.PutChatPrefs(-100123, ChatPrefs{ActiveCar: 3}); err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	if err := st.SetMarker("drive:3", Marker{ID: 7, At: at}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Put("alerts", nil); err == nil {
+		t.Error("关闭后写入应返回错误")
+	}
+
+	st, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	var alerts map[string]int
+	if found, err := st.Get("alerts", &alerts); err != nil || !found || alerts["100"] != 20 {
+		t.Errorf("alerts = %v, %v, %v", alerts, found, err)
+	}
+	if prefs, found, err := st.ChatPrefs(-100123); err != nil || !found || prefs.ActiveCar != 3 {
+		t.Errorf("会话偏好 = %+v, %v, %v", prefs, found, err)
+	}
+	if m, found, err := st.Marker("drive:3"); err != nil || !found || m.ID != 7 || !m.At.Equal(at) {
+		t.Errorf("事件标记 = %+v, %v, %v", m, found, err)
+	}
+}
+
+func TestNewerSchemaRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "teslamate-bot.db")
+	st, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metaBucket).Put(schemaVersionKey, []byte(strconv.Itoa(SchemaVersion+1)))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Close()
+
+	if st, err := Open(path); err == nil {
+		st.Close()
+		t.Fatal("高于当前版本的数据库应拒绝打开")
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	st, err := Open(filepath.Join(t.TempDir(), "teslamate-bot.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	type snapshot struct {
+		Level int `json:"level"`
+	}
+	var latest snapshot
+	if _, found, err := st.LatestSnapshot(1, &latest); err != nil || found {
+		t.Fatalf("没有快照时 found = %v, err = %v", found, err)
+	}
+
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		if err := st.PutSnapshot(1, base.Add(time.Duration(i)*time.Hour), snapshot{Level: 80 - i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := st.PutSnapshot(2, base, snapshot{Level: 50}); err != nil {
+		t.Fatal(err)
+	}
+
+	at, found, err := st.LatestSnapshot(1, &latest)
+	if err != nil || !found || latest.Level != 76 || !at.Equal(base.Add(4*time.Hour)) {
+		t.Errorf("最新快照 = %+v @ %v, %v, %v", latest, at, found, err)
+	}
+
+	var levels []int
+	err = st.Snapshots(1, base.Add(2*time.Hour), func(_ time.Time, raw json.RawMessage) error {
+		var s snapshot
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return err
+		}
+		levels = append(levels, s.Level)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{78, 77, 76}; !reflect.DeepEqual(levels, want) {
+		t.Errorf("快照 = %v, 期望 %v", levels, want)
+	}
+
+	pruned, err := st.PruneSnapshots(base.Add(3 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != 4 {
+		t.Errorf("清理了 %d 条快照，期望 4 条", pruned)
+	}
+	n := 0
+	st.Snapshots(1, time.Time{}, func(time.Time, json.RawMessage) error { n++; return nil })
+	if n != 2 {
+		t.Errorf("清理后剩余 %d 条快照，期望 2 条", n)
+	}
+}